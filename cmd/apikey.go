@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/yangirxd/goods-service/internal/auth"
+	"github.com/yangirxd/goods-service/internal/db"
+	"github.com/yangirxd/goods-service/internal/models"
+	"github.com/yangirxd/goods-service/internal/repository"
+)
+
+// apikey выпускает ключ API напрямую в PostgreSQL. Нужен, чтобы получить
+// первый ключ со scope admin, когда выпускать ключи через API ещё некому.
+// Таблица api_keys должна быть создана миграциями serve.
+func apikey(args []string) {
+	flags := flag.NewFlagSet("apikey", flag.ExitOnError)
+	name := flags.String("name", "", "key name, e.g. the job that uses it")
+	scopes := flags.String("scopes", "", "comma-separated scopes: "+strings.Join(auth.Scopes, ", "))
+	ttl := flags.Duration("ttl", 0, "key lifetime, e.g. 720h; 0 means the key never expires")
+
+	a := newApp(flags, args)
+	cfg, logger := a.cfg, a.logger
+
+	input := &models.APIKeyCreate{
+		Name:   *name,
+		Scopes: strings.Split(*scopes, ","),
+	}
+	if *name == "" || *scopes == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+	if *ttl > 0 {
+		expiresAt := time.Now().Add(*ttl)
+		input.ExpiresAt = &expiresAt
+	}
+
+	record, key, err := auth.NewAPIKey(input, "cli")
+	if err != nil {
+		fatal("issue api key", err)
+	}
+
+	pg, err := db.NewPostgres(cfg.Postgres, logger)
+	if err != nil {
+		fatal("connect to postgres", err)
+	}
+	defer pg.Close()
+
+	created, err := repository.NewAPIKeyRepository(pg).Create(context.Background(), record)
+	if err != nil {
+		fatal("issue api key", err)
+	}
+	logger.Info("api key issued", "api_key_id", created.ID, "prefix", created.Prefix, "scopes", created.Scopes, "by", created.CreatedBy)
+
+	// Ключ выводится отдельной строкой JSON после журнала
+	enc := json.NewEncoder(os.Stdout)
+	if err := enc.Encode(models.APIKeyIssued{APIKey: *created, Key: key}); err != nil {
+		fatal("print api key", err)
+	}
+
+	if err := a.shutdownTracing(context.Background()); err != nil {
+		logger.Error("shut down tracing", "error", err)
+	}
+}
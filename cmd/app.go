@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/yangirxd/goods-service/internal/config"
+	"github.com/yangirxd/goods-service/internal/handler"
+	"github.com/yangirxd/goods-service/internal/health"
+	"github.com/yangirxd/goods-service/internal/logging"
+	"github.com/yangirxd/goods-service/internal/metrics"
+	"github.com/yangirxd/goods-service/internal/tracing"
+)
+
+// app общая для команд часть: настройки, логгер и трассировка
+type app struct {
+	cfg             *config.Config
+	logger          *slog.Logger
+	shutdownTracing func(context.Context) error
+}
+
+// newApp разбирает флаги команды, добавив к ним -config, загружает
+// настройки и настраивает логирование и трассировку
+func newApp(flags *flag.FlagSet, args []string) *app {
+	command := flags.Name()
+	configPath := flags.String("config", os.Getenv("CONFIG_FILE"), "path to the YAML config file")
+	flags.Parse(args)
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fatal("load configuration", err)
+	}
+
+	logger, err := logging.New(os.Stdout, cfg.Log.Format, cfg.Log.Level)
+	if err != nil {
+		fatal("configure logging", err)
+	}
+	logger = logger.With("command", command)
+	slog.SetDefault(logger)
+	logger.Info("configuration loaded", "file", *configPath, "config", cfg)
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		fatal("set up tracing", err)
+	}
+
+	return &app{
+		cfg:             cfg,
+		logger:          logger,
+		shutdownTracing: shutdownTracing,
+	}
+}
+
+// newChecker создаёт проверку готовности. Из HEALTH_CRITICAL учитываются
+// только зависимости, которые использует команда.
+func (a *app) newChecker(checks map[string]health.CheckFunc) *health.Checker {
+	var critical []string
+	for _, name := range a.cfg.Health.Critical {
+		if _, ok := checks[name]; ok {
+			critical = append(critical, name)
+		}
+	}
+
+	checker := health.NewChecker(critical, a.cfg.Health.Timeout)
+	// Порядок регистрации задаёт порядок зависимостей в ответе /readyz
+	for _, name := range config.Dependencies {
+		if check, ok := checks[name]; ok {
+			checker.Register(name, check)
+		}
+	}
+	if err := checker.Validate(); err != nil {
+		fatal("invalid HEALTH_CRITICAL", err)
+	}
+	return checker
+}
+
+// newRouter создаёт роутер с общими middleware и служебными маршрутами
+// /healthz, /readyz и /metrics
+func (a *app) newRouter(checker *health.Checker) *gin.Engine {
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	// Без доверенных прокси IP клиента — адрес соединения, иначе
+	// X-Forwarded-For позволял бы обойти ограничение частоты по IP
+	if err := r.SetTrustedProxies(a.cfg.HTTP.TrustedProxies); err != nil {
+		fatal("configure trusted proxies", err)
+	}
+	// Recovery последним: паника в обработчике попадает в журнал с ID запроса
+	// и трассировки, а журнал запросов, span и метрики видят ответ 500
+	r.Use(logging.Middleware(a.logger), tracing.Middleware(), metrics.Middleware(), logging.Recovery(a.logger))
+
+	healthHandler := handler.NewHealthHandler(checker)
+	r.GET("/healthz", healthHandler.Liveness)
+	r.GET("/readyz", healthHandler.Readiness)
+	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
+	return r
+}
+
+// run запускает HTTP-сервер и ждёт SIGINT или SIGTERM. После сигнала
+// сервер перестаёт принимать запросы, затем shutdown останавливает
+// компоненты команды, а в конце отправляются накопленные span'ы.
+func (a *app) run(router http.Handler, shutdown func(ctx context.Context)) {
+	srv := &http.Server{
+		Addr:    a.cfg.HTTP.Addr,
+		Handler: router,
+	}
+
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fatal("run http server", err)
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+	// Повторный сигнал завершает процесс сразу
+	stop()
+
+	a.logger.Info("shutting down", "timeout", a.cfg.HTTP.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), a.cfg.HTTP.ShutdownTimeout)
+	defer cancel()
+
+	// Перестаём принимать запросы и дожидаемся выполняющихся:
+	// после этого новые события не публикуются
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		a.logger.Error("shut down http server", "error", err)
+	}
+
+	shutdown(shutdownCtx)
+
+	// Span'ы отправляются последними, включая span'ы последней записи в ClickHouse
+	if err := a.shutdownTracing(shutdownCtx); err != nil {
+		a.logger.Error("shut down tracing", "error", err)
+	}
+
+	a.logger.Info("stopped")
+}
+
+// fatal записывает ошибку и завершает процесс
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/yangirxd/goods-service/internal/config"
+	"github.com/yangirxd/goods-service/internal/health"
+)
+
+func TestNewCheckerSkipsUnusedDependencies(t *testing.T) {
+	cfg := config.Default()
+	cfg.Health.Critical = []string{"postgres", "nats"}
+	cfg.Health.Timeout = time.Second
+	a := &app{cfg: &cfg}
+
+	down := func(context.Context) error { return errors.New("connection refused") }
+	up := func(context.Context) error { return nil }
+
+	// consume не использует Postgres, поэтому он не считается критичным
+	checker := a.newChecker(map[string]health.CheckFunc{
+		"clickhouse": down,
+		"nats":       up,
+	})
+
+	resp := checker.Check(context.Background())
+	if resp.Status != health.StatusUp {
+		t.Errorf("Status = %q, want %q", resp.Status, health.StatusUp)
+	}
+
+	// Зависимости перечислены в порядке config.Dependencies
+	if len(resp.Dependencies) != 2 {
+		t.Fatalf("Dependencies = %+v, want nats and clickhouse", resp.Dependencies)
+	}
+	if d := resp.Dependencies[0]; d.Name != "nats" || !d.Critical {
+		t.Errorf("Dependencies[0] = %+v, want critical nats", d)
+	}
+	if d := resp.Dependencies[1]; d.Name != "clickhouse" || d.Critical || d.Status != health.StatusDown {
+		t.Errorf("Dependencies[1] = %+v, want optional clickhouse down", d)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"flag"
+
+	"github.com/yangirxd/goods-service/internal/clickhouse"
+	"github.com/yangirxd/goods-service/internal/health"
+	"github.com/yangirxd/goods-service/internal/queue"
+)
+
+// consume запускает потребителя событий: читает их из NATS и пакетами
+// записывает в ClickHouse. Экземпляры с одинаковым NATS_QUEUE_GROUP
+// делят события между собой. HTTP-сервер отдаёт только /healthz,
+// /readyz и /metrics.
+func consume(args []string) {
+	a := newApp(flag.NewFlagSet("consume", flag.ExitOnError), args)
+	cfg, logger := a.cfg, a.logger
+
+	// Подключение к ClickHouse
+	chClient, err := clickhouse.NewClient(cfg.ClickHouse, logger)
+	if err != nil {
+		fatal("connect to clickhouse", err)
+	}
+
+	// Создание и запуск потребителя логов
+	logConsumer, err := queue.NewLogConsumer(cfg.NATS.URL, cfg.NATS.QueueGroup, chClient, logger)
+	if err != nil {
+		fatal("create log consumer", err)
+	}
+
+	go func() {
+		if err := logConsumer.Start(); err != nil {
+			fatal("run log consumer", err)
+		}
+	}()
+
+	checker := a.newChecker(map[string]health.CheckFunc{
+		"nats": func(ctx context.Context) error {
+			return logConsumer.Ping()
+		},
+		"clickhouse": chClient.Ping,
+	})
+
+	a.run(a.newRouter(checker), func(ctx context.Context) {
+		// Обрабатываем полученные сообщения и записываем последний пакет в ClickHouse
+		if err := logConsumer.Shutdown(ctx); err != nil {
+			logger.Error("shut down log consumer", "error", err)
+		}
+		if err := chClient.Close(); err != nil {
+			logger.Error("close clickhouse", "error", err)
+		}
+	})
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	swaggerFiles "github.com/swaggo/files"
+	ginSwagger "github.com/swaggo/gin-swagger"
+	"github.com/yangirxd/goods-service/internal/auth"
+	"github.com/yangirxd/goods-service/internal/cache"
+	"github.com/yangirxd/goods-service/internal/clickhouse"
+	"github.com/yangirxd/goods-service/internal/db"
+	"github.com/yangirxd/goods-service/internal/handler"
+	"github.com/yangirxd/goods-service/internal/health"
+	"github.com/yangirxd/goods-service/internal/metrics"
+	"github.com/yangirxd/goods-service/internal/queue"
+	"github.com/yangirxd/goods-service/internal/ratelimit"
+	"github.com/yangirxd/goods-service/internal/repository"
+	"github.com/yangirxd/goods-service/internal/tracing"
+)
+
+// serve запускает HTTP API. События публикуются в NATS, а в ClickHouse
+// сервис только читает историю и аналитику.
+func serve(args []string) {
+	a := newApp(flag.NewFlagSet("serve", flag.ExitOnError), args)
+	cfg, logger := a.cfg, a.logger
+
+	// Подключение к Postgres
+	pg, err := db.NewPostgres(cfg.Postgres, logger)
+	if err != nil {
+		fatal("connect to postgres", err)
+	}
+
+	// Запуск миграций
+	if err := db.RunMigrations(pg, "migrations/postgres", logger); err != nil {
+		fatal("run migrations", err)
+	}
+
+	// Подключение к Redis
+	redisClient, err := cache.NewRedisClient(cfg.Redis)
+	if err != nil {
+		fatal("configure redis", err)
+	}
+	redisClient.AddHook(tracing.RedisHook{})
+
+	// Подключение к NATS
+	publisher, err := queue.NewLogger(cfg.NATS.URL, cfg.NATS.PublishLegacy)
+	if err != nil {
+		fatal("connect to nats", err)
+	}
+
+	// Подключение к ClickHouse
+	chClient, err := clickhouse.NewClient(cfg.ClickHouse, logger)
+	if err != nil {
+		fatal("connect to clickhouse", err)
+	}
+
+	goodsRepo := repository.NewGoodsRepository(pg)
+	goodsCache := cache.NewGoodsCache(redisClient, cfg.Cache.Config, logger)
+	goodsCache.Start()
+	metrics.RegisterCache(goodsCache)
+
+	if cfg.Cache.WarmupTopN > 0 {
+		warmUpCache(goodsRepo, goodsCache, cfg.Cache.WarmupTopN, logger)
+	}
+
+	// Инвалидация кэша при изменениях goods в обход сервиса
+	listenerCtx, stopListener := context.WithCancel(context.Background())
+	listenerDone := make(chan struct{})
+	listener := db.NewListener(cfg.Postgres.DSN, func(ctx context.Context, change db.GoodChange) {
+		if err := goodsCache.Invalidate(ctx, change.ProjectID, change.IDs, goodsRepo.GetMany); err != nil {
+			logger.ErrorContext(ctx, "invalidate cached goods", "project_id", change.ProjectID, "goods", len(change.IDs), "error", err)
+		}
+	}, logger)
+	go func() {
+		defer close(listenerDone)
+		listener.Run(listenerCtx)
+	}()
+
+	// Проверка готовности зависимостей
+	checker := a.newChecker(map[string]health.CheckFunc{
+		"postgres": pg.PingContext,
+		"redis":    goodsCache.Ping,
+		"nats": func(ctx context.Context) error {
+			return publisher.Ping()
+		},
+		"clickhouse": chClient.Ping,
+	})
+	checker.SetDegraded("redis", goodsCache.Degraded)
+
+	// Роли в проектах; без аутентификации субъекта нет и доступны все проекты
+	memberRepo := repository.NewProjectMemberRepository(pg)
+	projectAccess := auth.NewProjects(memberRepo)
+
+	goodsHandler := handler.NewGoodsHandler(goodsRepo, goodsCache, publisher, projectAccess, logger)
+	historyHandler := handler.NewHistoryHandler(chClient, goodsRepo, projectAccess)
+	analyticsHandler := handler.NewAnalyticsHandler(chClient, projectAccess)
+
+	apiKeyRepo := repository.NewAPIKeyRepository(pg)
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyRepo, logger)
+	memberHandler := handler.NewProjectMemberHandler(memberRepo, projectAccess, logger)
+
+	// Аутентификация запросов к API; без AUTH_ENABLED API анонимный,
+	// а scope не проверяются
+	var (
+		protected    []gin.HandlerFunc
+		jwtAuth      *auth.JWT
+		requireScope = func(string) gin.HandlerFunc { return func(c *gin.Context) { c.Next() } }
+	)
+	if cfg.Auth.Enabled {
+		authenticators := []auth.Authenticator{auth.NewAPIKeys(apiKeyRepo, logger)}
+		if cfg.Auth.HasJWT() {
+			jwtAuth, err = auth.NewJWT(cfg.Auth, logger)
+			if err != nil {
+				fatal("configure authentication", err)
+			}
+			authenticators = append(authenticators, jwtAuth)
+		}
+		protected = append(protected, auth.Middleware(logger, authenticators...))
+		requireScope = auth.RequireScope
+	}
+	read, write := requireScope(auth.ScopeGoodsRead), requireScope(auth.ScopeGoodsWrite)
+
+	// Ограничение частоты запросов после аутентификации: ведро выбирается
+	// по субъекту запроса, а без него — по IP
+	if cfg.RateLimit.Enabled {
+		limiter := ratelimit.NewLimiter(redisClient, cfg.RateLimit, goodsCache.Degraded)
+		protected = append(protected, limiter.Middleware(logger))
+	}
+
+	r := a.newRouter(checker)
+
+	// Swagger документация
+	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+
+	goods := r.Group("/goods", protected...)
+	{
+		goods.POST("/create", write, goodsHandler.Create)
+		goods.GET("/get/:id", read, goodsHandler.Get)
+		goods.PATCH("/update/:id", write, goodsHandler.Update)
+		goods.DELETE("/remove/:id", write, goodsHandler.Delete)
+		goods.GET("/list", read, goodsHandler.List)
+		goods.PATCH("/reprioritize", write, goodsHandler.Reprioritize)
+		goods.GET("/:id/history", read, historyHandler.GoodHistory)
+	}
+
+	projects := r.Group("/projects", protected...)
+	{
+		projects.GET("/:id/history", read, historyHandler.ProjectHistory)
+	}
+
+	analytics := r.Group("/analytics", protected...)
+	{
+		analytics.GET("/daily", read, analyticsHandler.Daily)
+		analytics.GET("/reprioritized", read, analyticsHandler.Reprioritized)
+		analytics.GET("/churn", read, analyticsHandler.Churn)
+	}
+
+	// Управление ключами API и участниками проектов доступно только
+	// с включённой аутентификацией
+	if cfg.Auth.Enabled {
+		projects.GET("/:id/members", read, memberHandler.List)
+		projects.PUT("/:id/members/:subject", write, memberHandler.Put)
+		projects.DELETE("/:id/members/:subject", write, memberHandler.Delete)
+
+		admin := r.Group("/admin", append(protected, auth.RequireScope(auth.ScopeAdmin))...)
+		{
+			admin.POST("/api-keys", apiKeyHandler.Create)
+			admin.GET("/api-keys", apiKeyHandler.List)
+			admin.DELETE("/api-keys/:id", apiKeyHandler.Revoke)
+		}
+	}
+
+	a.run(r, func(ctx context.Context) {
+		// Отправляем в NATS уже опубликованные события
+		if err := publisher.Drain(ctx); err != nil {
+			logger.Error("drain event publisher", "error", err)
+		}
+
+		// Закрываем кэш и соединения с хранилищами
+		if jwtAuth != nil {
+			jwtAuth.Close()
+		}
+		// Слушатель обращается к Redis, поэтому Redis закрывается после его остановки
+		stopListener()
+		select {
+		case <-listenerDone:
+		case <-ctx.Done():
+			logger.Error("stop goods change listener", "error", ctx.Err())
+		}
+		if err := goodsCache.Close(); err != nil {
+			logger.Error("close cache", "error", err)
+		}
+		if err := redisClient.Close(); err != nil {
+			logger.Error("close redis", "error", err)
+		}
+		if err := chClient.Close(); err != nil {
+			logger.Error("close clickhouse", "error", err)
+		}
+		if err := pg.Close(); err != nil {
+			logger.Error("close postgres", "error", err)
+		}
+	})
+}
+
+// warmUpCache загружает в кэш самые приоритетные товары каждого проекта
+func warmUpCache(repo *repository.GoodsRepository, goodsCache *cache.GoodsCache, topN int, logger *slog.Logger) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	goods, err := repo.TopByPriority(ctx, topN)
+	if err != nil {
+		logger.Error("warm up cache", "error", err)
+		return
+	}
+
+	if err := goodsCache.Warm(ctx, goods); err != nil {
+		logger.Error("warm up cache", "error", err)
+		return
+	}
+
+	logger.Info("cache warmed up", "goods", len(goods))
+}
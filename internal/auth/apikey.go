@@ -0,0 +1,218 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yangirxd/goods-service/internal/models"
+)
+
+// MethodAPIKey способ аутентификации по ключу в заголовке Authorization: ApiKey
+const MethodAPIKey = "api_key"
+
+// Ключ имеет вид gsk_<prefix>_<secret>: prefix открыт и служит для поиска
+// ключа, secret — 32 случайных байта. Хранится только SHA-256 всего ключа:
+// у случайного ключа такой энтропии медленный хеш не нужен.
+const (
+	apiKeyTag         = "gsk_"
+	apiKeyPrefixBytes = 8
+	apiKeySecretBytes = 32
+)
+
+const (
+	// touchInterval как часто отмечается использование одного ключа;
+	// совпадает с окном в запросе хранилища
+	touchInterval = time.Minute
+	// rejectTTL сколько отклонённый ключ отклоняется без обращения к хранилищу
+	rejectTTL = time.Minute
+	// maxRejectedKeys ограничивает память под отклонённые ключи
+	maxRejectedKeys = 10000
+)
+
+// APIKeyStore хранилище ключей API
+type APIKeyStore interface {
+	GetByPrefix(ctx context.Context, prefix string) (*models.APIKey, error)
+	TouchLastUsed(ctx context.Context, id int64) error
+}
+
+// NewAPIKey создаёт ключ API и возвращает запись для хранилища и сам ключ,
+// который нужно передать владельцу: восстановить его потом нельзя
+func NewAPIKey(input *models.APIKeyCreate, createdBy string) (*models.APIKey, string, error) {
+	for _, scope := range input.Scopes {
+		if !ValidScope(scope) {
+			return nil, "", fmt.Errorf("unknown scope %q, expected one of %v", scope, Scopes)
+		}
+	}
+
+	buf := make([]byte, apiKeyPrefixBytes+apiKeySecretBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, "", fmt.Errorf("generate api key: %w", err)
+	}
+	prefix := hex.EncodeToString(buf[:apiKeyPrefixBytes])
+	secret := base64.RawURLEncoding.EncodeToString(buf[apiKeyPrefixBytes:])
+	key := apiKeyTag + prefix + "_" + secret
+
+	record := &models.APIKey{
+		Name:      input.Name,
+		Prefix:    prefix,
+		Scopes:    input.Scopes,
+		CreatedBy: createdBy,
+		KeyHash:   hashAPIKey(key),
+	}
+	if input.ExpiresAt != nil {
+		// Столбец хранит время без зоны в UTC
+		expiresAt := input.ExpiresAt.UTC()
+		record.ExpiresAt = &expiresAt
+	}
+
+	return record, key, nil
+}
+
+func hashAPIKey(key string) []byte {
+	sum := sha256.Sum256([]byte(key))
+	return sum[:]
+}
+
+// APIKeys проверяет ключи из заголовка Authorization: ApiKey. Использование
+// ключа записывается в хранилище не чаще раза в touchInterval, а повторы
+// отклонённого ключа в течение rejectTTL не доходят до хранилища.
+type APIKeys struct {
+	store  APIKeyStore
+	logger *slog.Logger
+
+	mu sync.Mutex
+	// touched время последней записи об использовании по ID ключа
+	touched map[int64]time.Time
+	// rejected срок, до которого ключ отклоняется сразу, по хешу ключа
+	rejected map[string]time.Time
+}
+
+func NewAPIKeys(store APIKeyStore, logger *slog.Logger) *APIKeys {
+	return &APIKeys{
+		store:    store,
+		logger:   logger,
+		touched:  make(map[int64]time.Time),
+		rejected: make(map[string]time.Time),
+	}
+}
+
+// Authenticate проверяет ключ и отмечает его использование. Субъектом
+// становится apikey:<prefix>, поэтому события, вызванные запросами с ключом,
+// записываются в журнал с ним.
+func (a *APIKeys) Authenticate(r *http.Request) (*Principal, error) {
+	scheme, key, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "ApiKey") {
+		return nil, ErrNoCredentials
+	}
+	key = strings.TrimSpace(key)
+
+	rest, ok := strings.CutPrefix(key, apiKeyTag)
+	if !ok {
+		return nil, errors.New("malformed api key")
+	}
+	prefix, _, ok := strings.Cut(rest, "_")
+	if !ok || len(prefix) != hex.EncodedLen(apiKeyPrefixBytes) {
+		return nil, errors.New("malformed api key")
+	}
+
+	// Отклонённые ключи запоминаются по хешу всего ключа, а не по префиксу:
+	// чужой секрет с префиксом настоящего ключа не должен блокировать его
+	hash := hashAPIKey(key)
+	if a.recentlyRejected(string(hash)) {
+		return nil, fmt.Errorf("api key %s was rejected recently", prefix)
+	}
+
+	ctx := r.Context()
+	record, err := a.store.GetByPrefix(ctx, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+	if err := checkAPIKey(record, prefix, hash); err != nil {
+		a.reject(string(hash))
+		return nil, err
+	}
+
+	if a.shouldTouch(record.ID) {
+		if err := a.store.TouchLastUsed(ctx, record.ID); err != nil {
+			a.logger.WarnContext(ctx, "record api key use", "api_key_id", record.ID, "error", err)
+		}
+	}
+
+	return &Principal{
+		Subject: "apikey:" + prefix,
+		Method:  MethodAPIKey,
+		Scopes:  record.Scopes,
+		Claims: map[string]interface{}{
+			"api_key_id":   strconv.FormatInt(record.ID, 10),
+			"api_key_name": record.Name,
+		},
+	}, nil
+}
+
+// checkAPIKey проверяет, что ключ с хешем hash найден, не отозван и не истёк
+func checkAPIKey(record *models.APIKey, prefix string, hash []byte) error {
+	if record == nil || subtle.ConstantTimeCompare(record.KeyHash, hash) != 1 {
+		return fmt.Errorf("unknown api key %s", prefix)
+	}
+	if record.RevokedAt != nil {
+		return fmt.Errorf("api key %s is revoked", prefix)
+	}
+	if record.ExpiresAt != nil && !time.Now().Before(*record.ExpiresAt) {
+		return fmt.Errorf("api key %s has expired", prefix)
+	}
+	return nil
+}
+
+// shouldTouch сообщает, пора ли снова записать использование ключа
+func (a *APIKeys) shouldTouch(id int64) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+	if last, ok := a.touched[id]; ok && now.Sub(last) < touchInterval {
+		return false
+	}
+	a.touched[id] = now
+	return true
+}
+
+// recentlyRejected сообщает, что ключ с этим хешем недавно отклонён
+func (a *APIKeys) recentlyRejected(hash string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	until, ok := a.rejected[hash]
+	return ok && time.Now().Before(until)
+}
+
+// reject запоминает отклонённый ключ на rejectTTL
+func (a *APIKeys) reject(hash string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+	if len(a.rejected) >= maxRejectedKeys {
+		for h, until := range a.rejected {
+			if !now.Before(until) {
+				delete(a.rejected, h)
+			}
+		}
+		// Поток разных ключей: проще начать заново, чем вытеснять по одному
+		if len(a.rejected) >= maxRejectedKeys {
+			clear(a.rejected)
+		}
+	}
+	a.rejected[hash] = now.Add(rejectTTL)
+}
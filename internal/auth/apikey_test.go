@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/yangirxd/goods-service/internal/models"
+)
+
+type fakeKeyStore struct {
+	keys    map[string]*models.APIKey
+	lookups int
+	touches int
+}
+
+func (s *fakeKeyStore) GetByPrefix(_ context.Context, prefix string) (*models.APIKey, error) {
+	s.lookups++
+	return s.keys[prefix], nil
+}
+
+func (s *fakeKeyStore) TouchLastUsed(context.Context, int64) error {
+	s.touches++
+	return nil
+}
+
+func newKeyRequest(key string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/goods/list", nil)
+	r.Header.Set("Authorization", "ApiKey "+key)
+	return r
+}
+
+func TestAPIKeysTouchOncePerInterval(t *testing.T) {
+	record, key, err := NewAPIKey(&models.APIKeyCreate{Name: "ci"}, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	record.ID = 1
+	store := &fakeKeyStore{keys: map[string]*models.APIKey{record.Prefix: record}}
+	keys := NewAPIKeys(store, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	for i := 0; i < 3; i++ {
+		if _, err := keys.Authenticate(newKeyRequest(key)); err != nil {
+			t.Fatalf("Authenticate() error = %v", err)
+		}
+	}
+
+	if store.touches != 1 {
+		t.Errorf("TouchLastUsed called %d times, want 1", store.touches)
+	}
+}
+
+func TestAPIKeysRememberRejectedKeys(t *testing.T) {
+	record, key, err := NewAPIKey(&models.APIKeyCreate{Name: "ci"}, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &fakeKeyStore{keys: map[string]*models.APIKey{record.Prefix: record}}
+	keys := NewAPIKeys(store, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	// Чужой секрет с префиксом настоящего ключа
+	wrong := key[:len(key)-1] + "x"
+	if key[len(key)-1] == 'x' {
+		wrong = key[:len(key)-1] + "y"
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := keys.Authenticate(newKeyRequest(wrong)); err == nil {
+			t.Fatal("Authenticate() accepted a wrong secret")
+		}
+	}
+	if store.lookups != 1 {
+		t.Errorf("GetByPrefix called %d times for a rejected key, want 1", store.lookups)
+	}
+
+	// Настоящий ключ с тем же префиксом не блокируется
+	if _, err := keys.Authenticate(newKeyRequest(key)); err != nil {
+		t.Errorf("Authenticate() error = %v", err)
+	}
+}
@@ -0,0 +1,168 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yangirxd/goods-service/internal/logging"
+	"github.com/yangirxd/goods-service/internal/models"
+)
+
+// Ключи gin.Context, в которые Middleware кладёт данные аутентификации
+const (
+	ContextSubject = "auth.subject"
+	ContextClaims  = "auth.claims"
+)
+
+// ErrNoCredentials возвращает Authenticator, если запрос не содержит
+// учётных данных его вида. Тогда Middleware пробует следующий.
+var ErrNoCredentials = errors.New("no credentials")
+
+// ErrUnavailable оборачивает ошибки, из-за которых учётные данные не удалось
+// проверить, например недоступность хранилища ключей. На такие запросы
+// Middleware отвечает 500, а не 401.
+var ErrUnavailable = errors.New("authentication unavailable")
+
+// Scope разрешения субъекта
+const (
+	ScopeGoodsRead  = "goods:read"
+	ScopeGoodsWrite = "goods:write"
+	// ScopeAdmin управление ключами API и все роли во всех проектах
+	ScopeAdmin = "admin"
+)
+
+// Scopes все известные scope
+var Scopes = []string{ScopeGoodsRead, ScopeGoodsWrite, ScopeAdmin}
+
+// ValidScope сообщает, является ли строка известным scope
+func ValidScope(scope string) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Principal аутентифицированный субъект запроса
+type Principal struct {
+	// Subject идентификатор субъекта, например claim sub
+	Subject string
+	// Method способ аутентификации, например jwt
+	Method string
+	Scopes []string
+	Claims map[string]interface{}
+}
+
+// HasScope сообщает, разрешена ли субъекту операция
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticator проверяет учётные данные запроса
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+type principalKey struct{}
+
+// WithPrincipal возвращает контекст с субъектом запроса
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext возвращает субъект запроса из контекста
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok
+}
+
+// Subject возвращает идентификатор субъекта из контекста или пустую строку
+func Subject(ctx context.Context) string {
+	if p, ok := FromContext(ctx); ok {
+		return p.Subject
+	}
+	return ""
+}
+
+// Middleware пропускает только аутентифицированные запросы. Authenticator'ы
+// проверяются по очереди до первого, который распознал учётные данные.
+// Субъект кладётся в контекст запроса и в gin.Context, остальным отвечается 401.
+func Middleware(logger *slog.Logger, authenticators ...Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, a := range authenticators {
+			p, err := a.Authenticate(c.Request)
+			if errors.Is(err, ErrNoCredentials) {
+				continue
+			}
+			if errors.Is(err, ErrUnavailable) {
+				logger.ErrorContext(c.Request.Context(), "authenticate request", "error", err)
+				c.AbortWithStatusJSON(http.StatusInternalServerError, models.ErrorResponse{
+					Code:      2,
+					Message:   "errors.internal",
+					Details:   err.Error(),
+					RequestID: logging.RequestID(c.Request.Context()),
+				})
+				return
+			}
+			if err != nil {
+				logger.InfoContext(c.Request.Context(), "authentication failed", "error", err)
+				abort(c, "invalid credentials")
+				return
+			}
+
+			c.Set(ContextSubject, p.Subject)
+			c.Set(ContextClaims, p.Claims)
+			c.Request = c.Request.WithContext(WithPrincipal(c.Request.Context(), p))
+			c.Next()
+			return
+		}
+
+		abort(c, "authentication required")
+	}
+}
+
+// RequireScope пропускает только запросы субъектов с указанным scope,
+// остальным отвечает 403. Используется после Middleware.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p, ok := FromContext(c.Request.Context())
+		if !ok {
+			abort(c, "authentication required")
+			return
+		}
+
+		if !p.HasScope(scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, models.ErrorResponse{
+				Code:      5,
+				Message:   "errors.auth.forbidden",
+				Details:   "missing scope " + scope,
+				RequestID: logging.RequestID(c.Request.Context()),
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// abort отвечает 401. Подробности ошибки проверки пишутся только в журнал,
+// чтобы не подсказывать клиенту, что именно не так с токеном.
+func abort(c *gin.Context, details string) {
+	c.Writer.Header().Add("WWW-Authenticate", `Bearer realm="goods-service"`)
+	c.Writer.Header().Add("WWW-Authenticate", `ApiKey realm="goods-service"`)
+	c.AbortWithStatusJSON(http.StatusUnauthorized, models.ErrorResponse{
+		Code:      4,
+		Message:   "errors.auth.unauthenticated",
+		Details:   details,
+		RequestID: logging.RequestID(c.Request.Context()),
+	})
+}
@@ -0,0 +1,237 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// jwksTimeout ограничивает время загрузки JWKS по URL
+	jwksTimeout = 10 * time.Second
+	// jwksMinReload минимальный интервал внепланового обновления JWKS при
+	// неизвестном kid, чтобы токены с чужими kid не вызывали поток запросов
+	jwksMinReload = time.Minute
+	// maxJWKSSize ограничивает размер загружаемого JWKS
+	maxJWKSSize = 1 << 20
+)
+
+// jwk открытый ключ из JWKS (RFC 7517). Поддерживаются только ключи RSA.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// keySet хранит открытые ключи RS256 по kid
+type keySet struct {
+	fetch   func(ctx context.Context) ([]byte, error)
+	refresh time.Duration
+	logger  *slog.Logger
+
+	mu       sync.RWMutex
+	keys     map[string]*rsa.PublicKey
+	lastLoad time.Time
+	reloadMu sync.Mutex
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+func newFileKeySet(path string) *keySet {
+	return &keySet{
+		fetch: func(context.Context) ([]byte, error) {
+			return os.ReadFile(path)
+		},
+		stopCh: make(chan struct{}),
+	}
+}
+
+func newURLKeySet(url string, refresh time.Duration, logger *slog.Logger) *keySet {
+	client := &http.Client{Timeout: jwksTimeout}
+	return &keySet{
+		fetch: func(ctx context.Context) ([]byte, error) {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+			if err != nil {
+				return nil, err
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				return nil, err
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				return nil, fmt.Errorf("unexpected status %s", resp.Status)
+			}
+			return io.ReadAll(io.LimitReader(resp.Body, maxJWKSSize))
+		},
+		refresh: refresh,
+		logger:  logger,
+		stopCh:  make(chan struct{}),
+	}
+}
+
+// load загружает ключи и заменяет ими текущие
+func (s *keySet) load() error {
+	ctx, cancel := context.WithTimeout(context.Background(), jwksTimeout)
+	defer cancel()
+
+	data, err := s.fetch(ctx)
+	if err != nil {
+		return err
+	}
+
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.lastLoad = time.Now()
+	s.mu.Unlock()
+	return nil
+}
+
+// start запускает периодическое обновление ключей, если оно настроено
+func (s *keySet) start() {
+	if s.refresh <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(s.refresh)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := s.load(); err != nil {
+					// Остаются прежние ключи
+					s.logger.Warn("refresh jwks", "error", err)
+				}
+			case <-s.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+func (s *keySet) stop() {
+	s.stopOnce.Do(func() { close(s.stopCh) })
+}
+
+// get возвращает ключ по kid. Токен без kid принимается, только если
+// в наборе один ключ. Неизвестный kid может означать ротацию ключей,
+// поэтому JWKS по URL перечитывается, но не чаще jwksMinReload.
+func (s *keySet) get(kid string) (*rsa.PublicKey, error) {
+	if key, ok := s.lookup(kid); ok {
+		return key, nil
+	}
+
+	if s.refresh > 0 && s.reloadIfStale() {
+		if key, ok := s.lookup(kid); ok {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+func (s *keySet) lookup(kid string) (*rsa.PublicKey, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if kid == "" {
+		if len(s.keys) != 1 {
+			return nil, false
+		}
+		for _, key := range s.keys {
+			return key, true
+		}
+	}
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+// reloadIfStale перечитывает ключи, если они загружены давно, и сообщает,
+// изменился ли набор
+func (s *keySet) reloadIfStale() bool {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	s.mu.RLock()
+	stale := time.Since(s.lastLoad) >= jwksMinReload
+	s.mu.RUnlock()
+	if !stale {
+		return false
+	}
+
+	if err := s.load(); err != nil {
+		s.logger.Warn("reload jwks", "error", err)
+		return false
+	}
+	return true
+}
+
+// parseJWKS разбирает JWKS и возвращает ключи RSA для подписи
+func parseJWKS(data []byte) (map[string]*rsa.PublicKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("parse jwks: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		// Ключи других типов и назначений пропускаются
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") || (k.Alg != "" && k.Alg != "RS256") {
+			continue
+		}
+
+		key, err := k.rsaPublicKey()
+		if err != nil {
+			return nil, fmt.Errorf("jwks key %q: %w", k.Kid, err)
+		}
+		keys[k.Kid] = key
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("jwks contains no RS256 signing keys")
+	}
+	return keys, nil
+}
+
+func (k *jwk) rsaPublicKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("decode modulus: %w", err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, fmt.Errorf("decode exponent: %w", err)
+	}
+
+	exponent := new(big.Int).SetBytes(e)
+	if len(n) == 0 || !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+		return nil, errors.New("invalid rsa key")
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(exponent.Int64()),
+	}, nil
+}
@@ -0,0 +1,185 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// MethodJWT способ аутентификации по JWT в заголовке Authorization: Bearer
+const MethodJWT = "jwt"
+
+// Config настройки аутентификации. Ключи API принимаются всегда, когда
+// аутентификация включена. Подпись JWT HS256 проверяется общим секретом,
+// RS256 — открытыми ключами из JWKS в файле или по URL.
+type Config struct {
+	// Enabled включает аутентификацию для /goods, /projects, /analytics и /admin
+	Enabled  bool   `yaml:"enabled"`
+	Secret   string `yaml:"secret"`
+	JWKSFile string `yaml:"jwks_file"`
+	JWKSURL  string `yaml:"jwks_url"`
+	// JWKSRefresh как часто перечитывать JWKS по URL
+	JWKSRefresh time.Duration `yaml:"jwks_refresh"`
+	// Issuer и Audience, если заданы, должны совпадать с claims iss и aud
+	Issuer   string `yaml:"issuer"`
+	Audience string `yaml:"audience"`
+	// Leeway допустимое расхождение часов при проверке exp и nbf
+	Leeway time.Duration `yaml:"leeway"`
+	// AdminScope значение claim scope или scp, которое даёт JWT scope admin.
+	// Пустое значение означает, что admin по JWT не выдаётся.
+	AdminScope string `yaml:"admin_scope"`
+}
+
+// DefaultConfig возвращает настройки по умолчанию: аутентификация выключена
+func DefaultConfig() Config {
+	return Config{
+		JWKSRefresh: 5 * time.Minute,
+		Leeway:      30 * time.Second,
+	}
+}
+
+// HasJWT сообщает, настроен ли хотя бы один источник ключей JWT
+func (c Config) HasJWT() bool {
+	return c.Secret != "" || c.JWKSFile != "" || c.JWKSURL != ""
+}
+
+// JWT проверяет токены из заголовка Authorization: Bearer
+type JWT struct {
+	parser     *jwt.Parser
+	secret     []byte
+	keys       *keySet
+	adminScope string
+}
+
+// NewJWT создаёт проверку JWT. Если задан JWKS, ключи загружаются сразу,
+// а JWKS по URL затем обновляется в фоне до вызова Close.
+func NewJWT(cfg Config, logger *slog.Logger) (*JWT, error) {
+	var methods []string
+	j := &JWT{adminScope: cfg.AdminScope}
+
+	if cfg.Secret != "" {
+		j.secret = []byte(cfg.Secret)
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+
+	switch {
+	case cfg.JWKSFile != "":
+		j.keys = newFileKeySet(cfg.JWKSFile)
+	case cfg.JWKSURL != "":
+		j.keys = newURLKeySet(cfg.JWKSURL, cfg.JWKSRefresh, logger)
+	}
+	if j.keys != nil {
+		if err := j.keys.load(); err != nil {
+			return nil, fmt.Errorf("load jwks: %w", err)
+		}
+		j.keys.start()
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+
+	if len(methods) == 0 {
+		return nil, errors.New("no jwt secret or jwks configured")
+	}
+
+	// Список алгоритмов строго ограничен настроенными ключами, иначе
+	// открытый RSA-ключ можно было бы подставить как секрет HS256
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(methods),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(cfg.Leeway),
+	}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+	j.parser = jwt.NewParser(opts...)
+
+	return j, nil
+}
+
+// Authenticate проверяет подпись и claims токена
+func (j *JWT) Authenticate(r *http.Request) (*Principal, error) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return nil, ErrNoCredentials
+	}
+
+	claims := jwt.MapClaims{}
+	if _, err := j.parser.ParseWithClaims(strings.TrimSpace(token), claims, j.key); err != nil {
+		return nil, err
+	}
+
+	subject, err := claims.GetSubject()
+	if err != nil {
+		return nil, err
+	}
+	if subject == "" {
+		return nil, errors.New("token has no sub claim")
+	}
+
+	return &Principal{
+		Subject: subject,
+		Method:  MethodJWT,
+		Scopes:  j.scopes(claims),
+		Claims:  claims,
+	}, nil
+}
+
+// scopes возвращает scope пользователя: чтение и изменение товаров
+// доступны любому действительному токену, а admin — только если claim
+// scope (строка через пробел) или scp (строка или массив) содержит
+// настроенный AdminScope. Scope admin от IdP сам по себе не принимается:
+// он снимает ограничения ролей в проектах.
+func (j *JWT) scopes(claims jwt.MapClaims) []string {
+	scopes := []string{ScopeGoodsRead, ScopeGoodsWrite}
+	if j.adminScope == "" {
+		return scopes
+	}
+
+	var granted []string
+	if v, ok := claims["scope"].(string); ok {
+		granted = strings.Fields(v)
+	}
+	switch v := claims["scp"].(type) {
+	case string:
+		granted = append(granted, strings.Fields(v)...)
+	case []interface{}:
+		for _, s := range v {
+			if s, ok := s.(string); ok {
+				granted = append(granted, s)
+			}
+		}
+	}
+
+	for _, s := range granted {
+		if s == j.adminScope {
+			return append(scopes, ScopeAdmin)
+		}
+	}
+	return scopes
+}
+
+// key выбирает ключ проверки подписи по алгоритму и kid токена
+func (j *JWT) key(token *jwt.Token) (interface{}, error) {
+	switch token.Method.Alg() {
+	case jwt.SigningMethodHS256.Alg():
+		return j.secret, nil
+	case jwt.SigningMethodRS256.Alg():
+		kid, _ := token.Header["kid"].(string)
+		return j.keys.get(kid)
+	}
+	return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+}
+
+// Close останавливает обновление JWKS
+func (j *JWT) Close() {
+	if j.keys != nil {
+		j.keys.stop()
+	}
+}
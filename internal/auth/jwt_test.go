@@ -0,0 +1,328 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+var (
+	rsaKeysOnce sync.Once
+	rsaKeys     [2]*rsa.PrivateKey
+)
+
+// testRSAKey возвращает один из двух ключей RSA, общих для всех тестов
+func testRSAKey(t *testing.T, i int) *rsa.PrivateKey {
+	t.Helper()
+
+	rsaKeysOnce.Do(func() {
+		for i := range rsaKeys {
+			key, err := rsa.GenerateKey(rand.Reader, 2048)
+			if err != nil {
+				panic(err)
+			}
+			rsaKeys[i] = key
+		}
+	})
+	return rsaKeys[i]
+}
+
+// jwksJSON возвращает JWKS с открытыми ключами по kid
+func jwksJSON(t *testing.T, keys map[string]*rsa.PrivateKey) []byte {
+	t.Helper()
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	for kid, key := range keys {
+		set.Keys = append(set.Keys, jwk{
+			Kty: "RSA",
+			Kid: kid,
+			Use: "sig",
+			Alg: "RS256",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func writeJWKS(t *testing.T, keys map[string]*rsa.PrivateKey) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, jwksJSON(t, keys), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func newTestJWT(t *testing.T, cfg Config) *JWT {
+	t.Helper()
+
+	if cfg.Leeway == 0 {
+		cfg.Leeway = DefaultConfig().Leeway
+	}
+	j, err := NewJWT(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("NewJWT() error = %v", err)
+	}
+	t.Cleanup(j.Close)
+	return j
+}
+
+// claims возвращает действительные claims, поверх которых тесты меняют отдельные поля
+func claims(overrides jwt.MapClaims) jwt.MapClaims {
+	c := jwt.MapClaims{
+		"sub": "alice",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	for k, v := range overrides {
+		if v == nil {
+			delete(c, k)
+			continue
+		}
+		c[k] = v
+	}
+	return c
+}
+
+func signHS256(t *testing.T, secret string, c jwt.MapClaims) string {
+	t.Helper()
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, c).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, kid string, c jwt.MapClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, c)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func authenticate(j *JWT, token string) (*Principal, error) {
+	r := httptest.NewRequest(http.MethodGet, "/goods/list", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	return j.Authenticate(r)
+}
+
+func TestJWTAcceptsValidTokens(t *testing.T) {
+	key := testRSAKey(t, 0)
+	j := newTestJWT(t, Config{
+		Secret:   testSecret,
+		JWKSFile: writeJWKS(t, map[string]*rsa.PrivateKey{"k1": key}),
+	})
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"HS256", signHS256(t, testSecret, claims(nil))},
+		{"RS256", signRS256(t, key, "k1", claims(nil))},
+		// Единственный ключ в JWKS подходит и без kid
+		{"RS256 without kid", signRS256(t, key, "", claims(nil))},
+		{"expired within leeway", signHS256(t, testSecret, claims(jwt.MapClaims{"exp": time.Now().Add(-10 * time.Second).Unix()}))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := authenticate(j, tt.token)
+			if err != nil {
+				t.Fatalf("Authenticate() error = %v", err)
+			}
+			if p.Subject != "alice" || p.Method != MethodJWT {
+				t.Errorf("Authenticate() = %+v", p)
+			}
+			if !p.HasScope(ScopeGoodsRead) || !p.HasScope(ScopeGoodsWrite) || p.HasScope(ScopeAdmin) {
+				t.Errorf("Scopes = %v, want goods:read and goods:write", p.Scopes)
+			}
+		})
+	}
+}
+
+func TestJWTRejectsOtherAlgorithms(t *testing.T) {
+	key := testRSAKey(t, 0)
+	hs := newTestJWT(t, Config{Secret: testSecret})
+	rs := newTestJWT(t, Config{JWKSFile: writeJWKS(t, map[string]*rsa.PrivateKey{"k1": key})})
+
+	none, err := jwt.NewWithClaims(jwt.SigningMethodNone, claims(nil)).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hs384, err := jwt.NewWithClaims(jwt.SigningMethodHS384, claims(nil)).SignedString([]byte(testSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Открытый ключ RS256 в роли секрета HS256
+	publicKey, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		j     *JWT
+		token string
+	}{
+		{"none", hs, none},
+		{"none with jwks", rs, none},
+		{"HS384", hs, hs384},
+		{"RS256 without jwks", hs, signRS256(t, key, "k1", claims(nil))},
+		{"HS256 without secret", rs, signHS256(t, string(publicKey), claims(nil))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if p, err := authenticate(tt.j, tt.token); err == nil {
+				t.Errorf("Authenticate() = %+v, want an error", p)
+			}
+		})
+	}
+}
+
+func TestJWTRejectsInvalidClaims(t *testing.T) {
+	j := newTestJWT(t, Config{Secret: testSecret, Issuer: "https://idp.example.com/", Audience: "goods-service"})
+	valid := jwt.MapClaims{"iss": "https://idp.example.com/", "aud": "goods-service"}
+	with := func(overrides jwt.MapClaims) jwt.MapClaims {
+		c := claims(valid)
+		for k, v := range overrides {
+			c[k] = v
+		}
+		return c
+	}
+
+	if _, err := authenticate(j, signHS256(t, testSecret, with(nil))); err != nil {
+		t.Fatalf("Authenticate() error = %v for valid claims", err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"expired", signHS256(t, testSecret, with(jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()}))},
+		{"not yet valid", signHS256(t, testSecret, with(jwt.MapClaims{"nbf": time.Now().Add(time.Minute).Unix()}))},
+		{"no exp", signHS256(t, testSecret, claims(jwt.MapClaims{"exp": nil, "iss": valid["iss"], "aud": valid["aud"]}))},
+		{"no sub", signHS256(t, testSecret, claims(jwt.MapClaims{"sub": nil, "iss": valid["iss"], "aud": valid["aud"]}))},
+		{"other issuer", signHS256(t, testSecret, with(jwt.MapClaims{"iss": "https://evil.example.com/"}))},
+		{"other audience", signHS256(t, testSecret, with(jwt.MapClaims{"aud": "billing"}))},
+		{"wrong secret", signHS256(t, "fedcba9876543210fedcba9876543210", with(nil))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if p, err := authenticate(j, tt.token); err == nil {
+				t.Errorf("Authenticate() = %+v, want an error", p)
+			}
+		})
+	}
+}
+
+func TestJWTReloadsJWKSForUnknownKid(t *testing.T) {
+	oldKey, newKey := testRSAKey(t, 0), testRSAKey(t, 1)
+
+	var (
+		mu    sync.Mutex
+		keys  = map[string]*rsa.PrivateKey{"old": oldKey}
+		loads int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		loads++
+		w.Write(jwksJSON(t, keys))
+	}))
+	t.Cleanup(server.Close)
+
+	j := newTestJWT(t, Config{JWKSURL: server.URL, JWKSRefresh: time.Hour})
+
+	// IdP выпускает токены новым ключом
+	mu.Lock()
+	keys = map[string]*rsa.PrivateKey{"old": oldKey, "new": newKey}
+	mu.Unlock()
+	token := signRS256(t, newKey, "new", claims(nil))
+
+	// Ключи загружены только что, поэтому JWKS ещё не перечитывается
+	if _, err := authenticate(j, token); err == nil {
+		t.Fatal("Authenticate() accepted an unknown kid without reloading JWKS")
+	}
+
+	j.keys.mu.Lock()
+	j.keys.lastLoad = time.Now().Add(-jwksMinReload)
+	j.keys.mu.Unlock()
+
+	if _, err := authenticate(j, token); err != nil {
+		t.Fatalf("Authenticate() error = %v after key rotation", err)
+	}
+	// Следующий неизвестный kid не вызывает повторную загрузку
+	if _, err := authenticate(j, signRS256(t, newKey, "other", claims(nil))); err == nil {
+		t.Error("Authenticate() accepted a token with an unknown kid")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if loads != 2 {
+		t.Errorf("JWKS loaded %d times, want 2", loads)
+	}
+}
+
+func TestJWTAdminScope(t *testing.T) {
+	tests := []struct {
+		name       string
+		adminScope string
+		claims     jwt.MapClaims
+		want       bool
+	}{
+		{"admin without mapping", "", jwt.MapClaims{"scope": "openid admin"}, false},
+		{"mapped scope without mapping", "", jwt.MapClaims{"scope": "goods-service:admin"}, false},
+		{"admin with other mapping", "goods-service:admin", jwt.MapClaims{"scope": "admin"}, false},
+		{"mapped scope", "goods-service:admin", jwt.MapClaims{"scope": "openid goods-service:admin"}, true},
+		{"mapped scp string", "goods-service:admin", jwt.MapClaims{"scp": "goods-service:admin"}, true},
+		{"mapped scp array", "goods-service:admin", jwt.MapClaims{"scp": []string{"openid", "goods-service:admin"}}, true},
+		{"no scope", "goods-service:admin", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j := newTestJWT(t, Config{Secret: testSecret, AdminScope: tt.adminScope})
+
+			p, err := authenticate(j, signHS256(t, testSecret, claims(tt.claims)))
+			if err != nil {
+				t.Fatalf("Authenticate() error = %v", err)
+			}
+			if got := p.HasScope(ScopeAdmin); got != tt.want {
+				t.Errorf("HasScope(admin) = %v, want %v (scopes %v)", got, tt.want, p.Scopes)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// Роли субъекта в проекте. Каждая следующая включает права предыдущей:
+// viewer читает товары, editor ещё и изменяет их, admin ещё и управляет
+// участниками проекта.
+const (
+	RoleViewer = "viewer"
+	RoleEditor = "editor"
+	RoleAdmin  = "admin"
+)
+
+var roleRank = map[string]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleAdmin:  3,
+}
+
+// MembershipStore хранилище ролей субъектов в проектах
+type MembershipStore interface {
+	// Roles возвращает роли субъекта по ID проекта
+	Roles(ctx context.Context, subject string) (map[int64]string, error)
+}
+
+// Access права субъекта запроса на проекты
+type Access struct {
+	all   bool
+	roles map[int64]string
+}
+
+// AllProjects возвращает права на все проекты с любой ролью
+func AllProjects() *Access {
+	return &Access{all: true}
+}
+
+type accessKey struct{}
+
+// WithAccess возвращает контекст с правами субъекта запроса на проекты
+func WithAccess(ctx context.Context, a *Access) context.Context {
+	return context.WithValue(ctx, accessKey{}, a)
+}
+
+// AccessFromContext возвращает права на проекты из контекста
+func AccessFromContext(ctx context.Context) (*Access, bool) {
+	a, ok := ctx.Value(accessKey{}).(*Access)
+	return a, ok
+}
+
+// All сообщает, что субъекту доступны все проекты с любой ролью
+func (a *Access) All() bool {
+	return a.all
+}
+
+// Can сообщает, есть ли у субъекта в проекте роль не ниже указанной
+func (a *Access) Can(projectID int64, role string) bool {
+	if a.all {
+		return true
+	}
+	return roleRank[a.roles[projectID]] >= roleRank[role]
+}
+
+// Projects возвращает по возрастанию ID проекты, в которых у субъекта
+// роль не ниже указанной. Для All результат не имеет смысла.
+func (a *Access) Projects(role string) []int64 {
+	ids := make([]int64, 0, len(a.roles))
+	for id := range a.roles {
+		if a.Can(id, role) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// Projects определяет права субъектов на проекты по их ролям
+type Projects struct {
+	store MembershipStore
+}
+
+func NewProjects(store MembershipStore) *Projects {
+	return &Projects{store: store}
+}
+
+// Access возвращает права субъекта запроса. Без субъекта, то есть с
+// выключенной аутентификацией, и со scope admin доступны все проекты.
+func (p *Projects) Access(ctx context.Context) (*Access, error) {
+	principal, ok := FromContext(ctx)
+	if !ok || principal.HasScope(ScopeAdmin) {
+		return AllProjects(), nil
+	}
+
+	roles, err := p.store.Roles(ctx, principal.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("load project roles: %w", err)
+	}
+	return &Access{roles: roles}, nil
+}
@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+type fakeMembershipStore map[string]map[int64]string
+
+func (s fakeMembershipStore) Roles(_ context.Context, subject string) (map[int64]string, error) {
+	return s[subject], nil
+}
+
+func TestProjectsAccess(t *testing.T) {
+	projects := NewProjects(fakeMembershipStore{
+		"alice": {1: RoleViewer, 2: RoleEditor, 3: RoleAdmin},
+	})
+
+	tests := []struct {
+		name      string
+		principal *Principal
+		all       bool
+	}{
+		{"anonymous", nil, true},
+		{"admin scope", &Principal{Subject: "bob", Scopes: []string{ScopeAdmin}}, true},
+		{"member", &Principal{Subject: "alice", Scopes: []string{ScopeGoodsRead}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.principal != nil {
+				ctx = WithPrincipal(ctx, tt.principal)
+			}
+
+			access, err := projects.Access(ctx)
+			if err != nil {
+				t.Fatalf("Access() error = %v", err)
+			}
+			if access.All() != tt.all {
+				t.Errorf("All() = %v, want %v", access.All(), tt.all)
+			}
+		})
+	}
+}
+
+func TestAccessRoles(t *testing.T) {
+	projects := NewProjects(fakeMembershipStore{
+		"alice": {1: RoleViewer, 2: RoleEditor, 3: RoleAdmin},
+	})
+	access, err := projects.Access(WithPrincipal(context.Background(), &Principal{Subject: "alice"}))
+	if err != nil {
+		t.Fatalf("Access() error = %v", err)
+	}
+
+	tests := []struct {
+		projectID int64
+		role      string
+		want      bool
+	}{
+		{1, RoleViewer, true},
+		{1, RoleEditor, false},
+		{2, RoleEditor, true},
+		{2, RoleAdmin, false},
+		{3, RoleAdmin, true},
+		{4, RoleViewer, false},
+	}
+	for _, tt := range tests {
+		if got := access.Can(tt.projectID, tt.role); got != tt.want {
+			t.Errorf("Can(%d, %s) = %v, want %v", tt.projectID, tt.role, got, tt.want)
+		}
+	}
+
+	if got := access.Projects(RoleEditor); !reflect.DeepEqual(got, []int64{2, 3}) {
+		t.Errorf("Projects(editor) = %v, want [2 3]", got)
+	}
+	if got := access.Projects(RoleViewer); !reflect.DeepEqual(got, []int64{1, 2, 3}) {
+		t.Errorf("Projects(viewer) = %v, want [1 2 3]", got)
+	}
+}
@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// breaker размыкается после threshold ошибок подряд и не пропускает
+// запросы в течение cooldown. После паузы пропускается один пробный
+// запрос: успех замыкает цепь, ошибка снова размыкает её.
+type breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	return &breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+	}
+}
+
+// Allow сообщает, можно ли сейчас обращаться к Redis
+func (b *breaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return true
+	}
+	if b.probing || time.Now().Before(b.openUntil) {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// Success отмечает успешное обращение. Возвращает true, если цепь была разомкнута.
+func (b *breaker) Success() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	wasOpen := b.failures >= b.threshold
+	b.failures = 0
+	b.probing = false
+	return wasOpen
+}
+
+// Failure отмечает ошибку обращения. Возвращает true, если цепь только что разомкнулась.
+func (b *breaker) Failure() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	wasOpen := b.failures >= b.threshold
+	b.failures++
+	b.probing = false
+	if b.failures >= b.threshold {
+		b.openUntil = time.Now().Add(b.cooldown)
+	}
+	return !wasOpen && b.failures >= b.threshold
+}
+
+// Abort отмечает обращение, результат которого неизвестен
+func (b *breaker) Abort() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+// Open сообщает, разомкнута ли цепь
+func (b *breaker) Open() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.failures >= b.threshold
+}
@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBreakerOpensAfterThreshold(t *testing.T) {
+	b := newBreaker(2, time.Hour)
+
+	if !b.Allow() {
+		t.Fatal("Allow() = false for a closed breaker")
+	}
+	if b.Failure() {
+		t.Error("Failure() = true below the threshold")
+	}
+	if !b.Failure() {
+		t.Error("Failure() = false when the threshold is reached")
+	}
+	if !b.Open() || b.Allow() {
+		t.Error("breaker is not open after the threshold")
+	}
+	// Повторная ошибка не размыкает цепь заново
+	if b.Failure() {
+		t.Error("Failure() = true for an already open breaker")
+	}
+}
+
+func TestBreakerProbe(t *testing.T) {
+	b := newBreaker(1, 0)
+	b.Failure()
+
+	// После паузы пропускается ровно один пробный запрос
+	if !b.Allow() {
+		t.Fatal("Allow() = false after the cooldown")
+	}
+	if b.Allow() {
+		t.Error("Allow() = true while the probe is in flight")
+	}
+
+	// Прерванная проба не замыкает цепь, но позволяет повторить её
+	b.Abort()
+	if !b.Open() || !b.Allow() {
+		t.Error("probe is not allowed again after Abort")
+	}
+
+	if !b.Success() {
+		t.Error("Success() = false for an open breaker")
+	}
+	if b.Open() || !b.Allow() {
+		t.Error("breaker is not closed after a successful probe")
+	}
+	if b.Success() {
+		t.Error("Success() = true for a closed breaker")
+	}
+}
@@ -0,0 +1,180 @@
+package cache
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/yangirxd/goods-service/internal/models"
+)
+
+// Значения в Redis хранятся в конверте:
+//
+//	[версия кодека: 1 байт][хэш схемы: 4 байта][флаги: 1 байт][данные]
+//
+// Данные кодируются в MessagePack. Хэш схемы считается по именам и типам
+// полей, поэтому записи, сохранённые до изменения models.Good, не
+// декодируются с нулевыми значениями, а считаются промахом.
+const (
+	codecVersion = 2
+	headerSize   = 6
+
+	flagCompressed = 1 << 0
+	flagTombstone  = 1 << 1
+
+	// compressThreshold размер данных, начиная с которого они сжимаются
+	compressThreshold = 1024
+)
+
+// errStale означает, что запись сохранена другой версией кодека или схемы
+var errStale = errors.New("stale cache entry")
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+
+	goodSchema = schemaHash(reflect.TypeOf(models.Good{}))
+	listSchema = schemaHash(reflect.TypeOf(models.ListResponse{}))
+)
+
+// encodeGood кодирует товар, nil кодируется как отметка об отсутствии
+func encodeGood(good *models.Good) ([]byte, error) {
+	if good == nil {
+		return appendHeader(nil, goodSchema, flagTombstone), nil
+	}
+	return encode(goodSchema, good)
+}
+
+// decodeGood декодирует товар. Для отметки об отсутствии возвращает nil, nil.
+func decodeGood(data []byte) (*models.Good, error) {
+	payload, flags, err := readHeader(data, goodSchema)
+	if err != nil {
+		return nil, err
+	}
+	if flags&flagTombstone != 0 {
+		return nil, nil
+	}
+
+	good := &models.Good{}
+	if err := decode(payload, flags, good); err != nil {
+		return nil, err
+	}
+	// MessagePack возвращает время в зоне процесса, а товары из PostgreSQL — в UTC
+	good.CreatedAt = good.CreatedAt.UTC()
+	return good, nil
+}
+
+func encodeList(page *models.ListResponse) ([]byte, error) {
+	return encode(listSchema, page)
+}
+
+func decodeList(data []byte) (*models.ListResponse, error) {
+	payload, flags, err := readHeader(data, listSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.ListResponse{}
+	if err := decode(payload, flags, page); err != nil {
+		return nil, err
+	}
+	if page.Goods == nil {
+		page.Goods = []models.Good{}
+	}
+	for i := range page.Goods {
+		page.Goods[i].CreatedAt = page.Goods[i].CreatedAt.UTC()
+	}
+	return page, nil
+}
+
+func encode(schema uint32, v interface{}) ([]byte, error) {
+	payload, err := msgpack.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(payload) < compressThreshold {
+		return append(appendHeader(make([]byte, 0, headerSize+len(payload)), schema, 0), payload...), nil
+	}
+
+	buf := bytes.NewBuffer(appendHeader(make([]byte, 0, headerSize+len(payload)/2), schema, flagCompressed))
+	w, err := flate.NewWriter(buf, flate.BestSpeed)
+	if err != nil {
+		return nil, fmt.Errorf("compress: %w", err)
+	}
+	if _, err := w.Write(payload); err != nil {
+		return nil, fmt.Errorf("compress: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("compress: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func decode(payload []byte, flags byte, v interface{}) error {
+	if flags&flagCompressed != 0 {
+		r := flate.NewReader(bytes.NewReader(payload))
+		defer r.Close()
+
+		var err error
+		payload, err = io.ReadAll(r)
+		if err != nil {
+			return fmt.Errorf("decompress: %w", err)
+		}
+	}
+
+	return msgpack.Unmarshal(payload, v)
+}
+
+func appendHeader(b []byte, schema uint32, flags byte) []byte {
+	b = append(b, codecVersion)
+	b = binary.BigEndian.AppendUint32(b, schema)
+	return append(b, flags)
+}
+
+func readHeader(data []byte, schema uint32) (payload []byte, flags byte, err error) {
+	if len(data) < headerSize || data[0] != codecVersion || binary.BigEndian.Uint32(data[1:5]) != schema {
+		return nil, 0, errStale
+	}
+	return data[headerSize:], data[5], nil
+}
+
+// schemaHash возвращает контрольную сумму имён и типов полей
+func schemaHash(t reflect.Type) uint32 {
+	var sb strings.Builder
+	describe(&sb, t)
+	return crc32.ChecksumIEEE([]byte(sb.String()))
+}
+
+func describe(sb *strings.Builder, t reflect.Type) {
+	switch {
+	case t == timeType:
+		sb.WriteString("time")
+	case t.Kind() == reflect.Struct:
+		sb.WriteString("{")
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			sb.WriteString(field.Name)
+			sb.WriteString(" ")
+			describe(sb, field.Type)
+			sb.WriteString(";")
+		}
+		sb.WriteString("}")
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Pointer:
+		sb.WriteString(t.Kind().String())
+		sb.WriteString(" ")
+		describe(sb, t.Elem())
+	default:
+		sb.WriteString(t.Kind().String())
+	}
+}
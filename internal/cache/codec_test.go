@@ -0,0 +1,288 @@
+package cache
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yangirxd/goods-service/internal/models"
+)
+
+func TestGoodRoundTrip(t *testing.T) {
+	yekaterinburg := time.FixedZone("YEKT", 5*60*60)
+
+	tests := []struct {
+		name string
+		good *models.Good
+		want *models.Good
+	}{
+		{
+			name: "zero values",
+			good: &models.Good{},
+			want: &models.Good{},
+		},
+		{
+			name: "all fields",
+			good: &models.Good{
+				ID:          42,
+				ProjectID:   7,
+				Name:        "Товар",
+				Description: "Описание",
+				Priority:    -3,
+				Removed:     true,
+				CreatedAt:   time.Date(2024, 3, 1, 12, 30, 15, 123456789, time.UTC),
+			},
+			want: &models.Good{
+				ID:          42,
+				ProjectID:   7,
+				Name:        "Товар",
+				Description: "Описание",
+				Priority:    -3,
+				Removed:     true,
+				CreatedAt:   time.Date(2024, 3, 1, 12, 30, 15, 123456789, time.UTC),
+			},
+		},
+		{
+			name: "time zone",
+			good: &models.Good{ID: 1, CreatedAt: time.Date(2024, 3, 1, 17, 30, 0, 0, yekaterinburg)},
+			want: &models.Good{ID: 1, CreatedAt: time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)},
+		},
+		{
+			name: "compressed",
+			good: &models.Good{ID: 1, Description: strings.Repeat("длинное описание ", 200)},
+			want: &models.Good{ID: 1, Description: strings.Repeat("длинное описание ", 200)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := encodeGood(tt.good)
+			if err != nil {
+				t.Fatalf("encodeGood() error = %v", err)
+			}
+
+			got, err := decodeGood(data)
+			if err != nil {
+				t.Fatalf("decodeGood() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decodeGood() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGoodTombstone(t *testing.T) {
+	data, err := encodeGood(nil)
+	if err != nil {
+		t.Fatalf("encodeGood() error = %v", err)
+	}
+
+	got, err := decodeGood(data)
+	if err != nil {
+		t.Fatalf("decodeGood() error = %v", err)
+	}
+	if got != nil {
+		t.Errorf("decodeGood() = %+v, want nil", got)
+	}
+}
+
+func TestListRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		page *models.ListResponse
+		want *models.ListResponse
+	}{
+		{
+			name: "nil goods",
+			page: &models.ListResponse{Meta: models.ListMeta{Limit: 10}},
+			want: &models.ListResponse{Meta: models.ListMeta{Limit: 10}, Goods: []models.Good{}},
+		},
+		{
+			name: "empty goods",
+			page: &models.ListResponse{Goods: []models.Good{}},
+			want: &models.ListResponse{Goods: []models.Good{}},
+		},
+		{
+			name: "goods",
+			page: &models.ListResponse{
+				Meta: models.ListMeta{Total: 2, Limit: 10},
+				Goods: []models.Good{
+					{ID: 1, Name: "a", CreatedAt: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+					{ID: 2, Name: "b", CreatedAt: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)},
+				},
+			},
+			want: &models.ListResponse{
+				Meta: models.ListMeta{Total: 2, Limit: 10},
+				Goods: []models.Good{
+					{ID: 1, Name: "a", CreatedAt: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+					{ID: 2, Name: "b", CreatedAt: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := encodeList(tt.page)
+			if err != nil {
+				t.Fatalf("encodeList() error = %v", err)
+			}
+
+			got, err := decodeList(data)
+			if err != nil {
+				t.Fatalf("decodeList() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decodeList() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestNilPointersAndSlices проверяет, что кодек различает nil и пустые
+// значения, если они появятся в закэшированных структурах
+func TestNilPointersAndSlices(t *testing.T) {
+	type entry struct {
+		Good  *models.Good
+		IDs   []int64
+		Goods []models.Good
+	}
+	schema := schemaHash(reflect.TypeOf(entry{}))
+
+	tests := []struct {
+		name  string
+		entry entry
+	}{
+		{"nil", entry{}},
+		{"empty", entry{IDs: []int64{}, Goods: []models.Good{}}},
+		{"zero elements", entry{Good: &models.Good{ID: 1}, IDs: []int64{0, 1, 0}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := encode(schema, &tt.entry)
+			if err != nil {
+				t.Fatalf("encode() error = %v", err)
+			}
+
+			payload, flags, err := readHeader(data, schema)
+			if err != nil {
+				t.Fatalf("readHeader() error = %v", err)
+			}
+
+			var got entry
+			if err := decode(payload, flags, &got); err != nil {
+				t.Fatalf("decode() error = %v", err)
+			}
+			if got.Good != nil {
+				got.Good.CreatedAt = got.Good.CreatedAt.UTC()
+			}
+			want := tt.entry
+			if want.Good != nil {
+				good := *want.Good
+				good.CreatedAt = good.CreatedAt.UTC()
+				want.Good = &good
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("decode() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestStaleEntry(t *testing.T) {
+	data, err := encodeGood(&models.Good{ID: 1})
+	if err != nil {
+		t.Fatalf("encodeGood() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"other codec version", append([]byte{codecVersion + 1}, data[1:]...)},
+		{"other schema", append(appendHeader(nil, listSchema, 0), data[headerSize:]...)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeGood(tt.data); err != errStale {
+				t.Errorf("decodeGood() error = %v, want %v", err, errStale)
+			}
+		})
+	}
+}
+
+func benchmarkGood() *models.Good {
+	return &models.Good{
+		ID:          123456,
+		ProjectID:   42,
+		Name:        "Товар с обычным названием",
+		Description: "Описание товара средней длины, как у большинства записей",
+		Priority:    17,
+		CreatedAt:   time.Date(2024, 3, 1, 12, 30, 15, 0, time.UTC),
+	}
+}
+
+func BenchmarkEncodeGood(b *testing.B) {
+	good := benchmarkGood()
+
+	b.Run("json", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			data, err := json.Marshal(good)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.SetBytes(int64(len(data)))
+		}
+	})
+
+	b.Run("binary", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			data, err := encodeGood(good)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.SetBytes(int64(len(data)))
+		}
+	})
+}
+
+func BenchmarkDecodeGood(b *testing.B) {
+	good := benchmarkGood()
+
+	b.Run("json", func(b *testing.B) {
+		data, err := json.Marshal(good)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.SetBytes(int64(len(data)))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var decoded models.Good
+			if err := json.Unmarshal(data, &decoded); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("binary", func(b *testing.B) {
+		data, err := encodeGood(good)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.SetBytes(int64(len(data)))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := decodeGood(data); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
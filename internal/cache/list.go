@@ -0,0 +1,110 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/yangirxd/goods-service/internal/models"
+)
+
+// Страницы списка хранятся под ключом, включающим поколение проекта (или
+// общее поколение для списка по всем проектам). Любое изменение товара
+// увеличивает оба счётчика, и все страницы проекта становятся недостижимыми
+// за O(1). Старые страницы удаляет Redis по истечении TTL.
+const allGenerationKey = "goods:gen:all"
+
+// ListQuery параметры страницы списка товаров. Нулевой ProjectID означает все проекты.
+type ListQuery struct {
+	ProjectID int64
+	Limit     int
+	Offset    int
+}
+
+// ListLoadFunc загружает страницу списка из основного хранилища
+type ListLoadFunc func(ctx context.Context) (*models.ListResponse, error)
+
+func projectGenerationKey(projectID int64) string {
+	return fmt.Sprintf("goods:gen:project:%d", projectID)
+}
+
+// GetOrLoadList возвращает страницу списка из кэша, а при промахе загружает её через load
+func (c *GoodsCache) GetOrLoadList(ctx context.Context, q ListQuery, load ListLoadFunc) (*models.ListResponse, error) {
+	key, ok := c.listKey(ctx, q)
+	if !ok {
+		return load(ctx)
+	}
+
+	if data, ok := c.getRaw(ctx, key); ok {
+		if page, err := decodeList(data); err == nil {
+			return page, nil
+		}
+	}
+
+	result, err, _ := c.loads.Do(key, func() (interface{}, error) {
+		loadCtx := context.WithoutCancel(ctx)
+
+		page, err := load(loadCtx)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := encodeList(page)
+		if err != nil {
+			return nil, fmt.Errorf("encode list: %w", err)
+		}
+		if err := c.set(loadCtx, key, data, c.ttl()); err != nil {
+			c.logger.WarnContext(ctx, "cache list page", "key", key, "error", err)
+		}
+
+		return page, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*models.ListResponse), nil
+}
+
+// BumpProject делает недействительными все закэшированные страницы списка
+// проекта и страницы общего списка
+func (c *GoodsCache) BumpProject(ctx context.Context, projectID int64) error {
+	if !c.breaker.Allow() {
+		return ErrUnavailable
+	}
+
+	_, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Incr(ctx, projectGenerationKey(projectID))
+		pipe.Incr(ctx, allGenerationKey)
+		return nil
+	})
+	c.record(err)
+	if err != nil {
+		return fmt.Errorf("bump generation: %w", err)
+	}
+
+	return nil
+}
+
+// listKey возвращает ключ страницы для текущего поколения.
+// ok равен false, если поколение прочитать не удалось.
+func (c *GoodsCache) listKey(ctx context.Context, q ListQuery) (string, bool) {
+	if !c.breaker.Allow() {
+		return "", false
+	}
+
+	scope, genKey := "all", allGenerationKey
+	if q.ProjectID != 0 {
+		scope, genKey = fmt.Sprintf("project:%d", q.ProjectID), projectGenerationKey(q.ProjectID)
+	}
+
+	gen, err := c.client.Get(ctx, genKey).Int64()
+	if err != nil && err != redis.Nil {
+		c.record(err)
+		c.logger.WarnContext(ctx, "read list generation", "key", genKey, "error", err)
+		return "", false
+	}
+	c.record(nil)
+
+	return fmt.Sprintf("goods:list:%s:gen:%d:limit:%d:offset:%d", scope, gen, q.Limit, q.Offset), true
+}
@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/yangirxd/goods-service/internal/models"
+)
+
+func TestGetOrLoadListGenerations(t *testing.T) {
+	c, _ := newTestCache(t)
+	ctx := context.Background()
+
+	loads := map[int64]int{}
+	get := func(projectID int64) {
+		t.Helper()
+		_, err := c.GetOrLoadList(ctx, ListQuery{ProjectID: projectID, Limit: 10}, func(context.Context) (*models.ListResponse, error) {
+			loads[projectID]++
+			return &models.ListResponse{}, nil
+		})
+		if err != nil {
+			t.Fatalf("GetOrLoadList(%d) error = %v", projectID, err)
+		}
+	}
+
+	for _, projectID := range []int64{0, 1, 2} {
+		get(projectID)
+		get(projectID)
+	}
+
+	// Изменение в проекте 1 затрагивает его страницы и общий список
+	if err := c.BumpProject(ctx, 1); err != nil {
+		t.Fatalf("BumpProject() error = %v", err)
+	}
+	for _, projectID := range []int64{0, 1, 2} {
+		get(projectID)
+	}
+
+	want := map[int64]int{0: 2, 1: 2, 2: 1}
+	for projectID, n := range want {
+		if loads[projectID] != n {
+			t.Errorf("project %d loaded %d times, want %d", projectID, loads[projectID], n)
+		}
+	}
+}
+
+func TestDeleteMany(t *testing.T) {
+	c, server := newTestCache(t)
+	ctx := context.Background()
+
+	for _, id := range []int64{1, 2, 3} {
+		if err := c.Set(ctx, GoodKey(id), &models.Good{ID: id}); err != nil {
+			t.Fatalf("Set() error = %v", err)
+		}
+	}
+
+	if err := c.Delete(ctx, GoodKey(1), GoodKey(2)); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if server.Exists(GoodKey(1)) || server.Exists(GoodKey(2)) || !server.Exists(GoodKey(3)) {
+		t.Errorf("keys after Delete() = %v, want only %s", server.Keys(), GoodKey(3))
+	}
+}
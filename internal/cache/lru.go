@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/yangirxd/goods-service/internal/models"
+)
+
+type lruEntry struct {
+	key       string
+	good      *models.Good
+	expiresAt time.Time
+}
+
+// lru ограниченный по размеру кэш в памяти процесса с временем жизни записей.
+// Запись с good == nil означает, что товара нет в базе.
+type lru struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+func newLRU(size int, ttl time.Duration) *lru {
+	return &lru{
+		size:    size,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: make(map[string]*list.Element, size),
+	}
+}
+
+// Get возвращает запись и признак того, что она есть и не истекла
+func (l *lru) Get(key string) (*models.Good, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e, ok := l.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := e.Value.(*lruEntry)
+	if time.Now().After(entry.expiresAt) {
+		l.remove(e)
+		return nil, false
+	}
+
+	l.order.MoveToFront(e)
+	return copyGood(entry.good), true
+}
+
+// Set сохраняет запись, вытесняя самую давно использованную при переполнении
+func (l *lru) Set(key string, good *models.Good) {
+	good = copyGood(good)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	expiresAt := time.Now().Add(l.ttl)
+	if e, ok := l.entries[key]; ok {
+		entry := e.Value.(*lruEntry)
+		entry.good = good
+		entry.expiresAt = expiresAt
+		l.order.MoveToFront(e)
+		return
+	}
+
+	l.entries[key] = l.order.PushFront(&lruEntry{key: key, good: good, expiresAt: expiresAt})
+	if l.order.Len() > l.size {
+		l.remove(l.order.Back())
+	}
+}
+
+// Delete удаляет запись
+func (l *lru) Delete(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if e, ok := l.entries[key]; ok {
+		l.remove(e)
+	}
+}
+
+func (l *lru) remove(e *list.Element) {
+	l.order.Remove(e)
+	delete(l.entries, e.Value.(*lruEntry).key)
+}
+
+// copyGood защищает записи кэша от изменения вызывающей стороной
+func copyGood(good *models.Good) *models.Good {
+	if good == nil {
+		return nil
+	}
+	g := *good
+	return &g
+}
@@ -0,0 +1,67 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yangirxd/goods-service/internal/models"
+)
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	l := newLRU(2, time.Hour)
+	l.Set("a", &models.Good{ID: 1})
+	l.Set("b", &models.Good{ID: 2})
+
+	// Чтение делает a последней использованной, поэтому вытесняется b
+	l.Get("a")
+	l.Set("c", &models.Good{ID: 3})
+
+	if _, ok := l.Get("b"); ok {
+		t.Error("Get(b) found an evicted entry")
+	}
+	if good, ok := l.Get("a"); !ok || good.ID != 1 {
+		t.Errorf("Get(a) = %+v, %v, want the stored good", good, ok)
+	}
+	if good, ok := l.Get("c"); !ok || good.ID != 3 {
+		t.Errorf("Get(c) = %+v, %v, want the stored good", good, ok)
+	}
+}
+
+func TestLRUExpires(t *testing.T) {
+	l := newLRU(10, time.Millisecond)
+	l.Set("a", &models.Good{ID: 1})
+
+	time.Sleep(2 * time.Millisecond)
+	if _, ok := l.Get("a"); ok {
+		t.Error("Get(a) found an expired entry")
+	}
+}
+
+func TestLRUMissingGood(t *testing.T) {
+	l := newLRU(10, time.Hour)
+	l.Set("a", nil)
+
+	good, ok := l.Get("a")
+	if !ok || good != nil {
+		t.Errorf("Get(a) = %+v, %v, want nil, true", good, ok)
+	}
+
+	l.Delete("a")
+	if _, ok := l.Get("a"); ok {
+		t.Error("Get(a) found a deleted entry")
+	}
+}
+
+func TestLRUCopiesGoods(t *testing.T) {
+	l := newLRU(10, time.Hour)
+	good := &models.Good{ID: 1, Name: "a"}
+	l.Set("a", good)
+
+	good.Name = "b"
+	got, _ := l.Get("a")
+	got.Name = "c"
+
+	if got, _ := l.Get("a"); got.Name != "a" {
+		t.Errorf("Get(a).Name = %q, want the stored %q", got.Name, "a")
+	}
+}
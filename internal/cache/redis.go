@@ -2,29 +2,346 @@ package cache
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 	"github.com/yangirxd/goods-service/internal/models"
+	"golang.org/x/sync/singleflight"
 )
 
+const (
+	breakerThreshold = 5
+	breakerCooldown  = 30 * time.Second
+
+	// invalidationChannel канал Redis, через который реплики сообщают
+	// друг другу об удалённых ключах
+	invalidationChannel = "goods:cache:invalidate"
+)
+
+// ErrUnavailable возвращается, когда обращения к Redis приостановлены
+var ErrUnavailable = errors.New("cache unavailable")
+
+// Config задаёт параметры кэша
+type Config struct {
+	// TTL время жизни найденного товара
+	TTL time.Duration `yaml:"ttl"`
+	// TTLJitter верхняя граница случайной добавки к TTL, чтобы записи,
+	// созданные одновременно, не истекали одновременно
+	TTLJitter time.Duration `yaml:"ttl_jitter"`
+	// NegativeTTL время жизни отметки об отсутствии товара
+	NegativeTTL time.Duration `yaml:"negative_ttl"`
+	// LocalSize количество записей в кэше процесса перед Redis, 0 отключает его
+	LocalSize int `yaml:"local_size"`
+	// LocalTTL время жизни записи в кэше процесса. Ограничивает устаревание,
+	// если сообщение об инвалидации от другой реплики потерялось.
+	LocalTTL time.Duration `yaml:"local_ttl"`
+	// WriteThrough записывает в кэш новые значения изменённых товаров
+	// вместо удаления ключей
+	WriteThrough bool `yaml:"write_through"`
+}
+
+// DefaultConfig возвращает настройки кэша по умолчанию
+func DefaultConfig() Config {
+	return Config{
+		TTL:         time.Minute,
+		TTLJitter:   10 * time.Second,
+		NegativeTTL: 10 * time.Second,
+		LocalTTL:    5 * time.Second,
+	}
+}
+
+// LoadFunc загружает товар из основного хранилища. Отсутствие товара
+// обозначается результатом nil, nil.
+type LoadFunc func(ctx context.Context) (*models.Good, error)
+
+// ManyLoadFunc загружает товары по ID из основного хранилища. Отсутствующих
+// товаров нет в результате.
+type ManyLoadFunc func(ctx context.Context, ids []int64) ([]*models.Good, error)
+
+// GoodsCache кэширует товары в Redis и, если задан Config.LocalSize, в памяти
+// процесса перед Redis. Ошибки Redis при чтении считаются промахом, а после
+// нескольких ошибок подряд кэш переходит в деградированный режим и не
+// обращается к Redis до истечения паузы.
 type GoodsCache struct {
-	client *redis.Client
+	client     redis.UniversalClient
+	cfg        Config
+	breaker    *breaker
+	loads      singleflight.Group
+	local      *lru
+	instanceID string
+	pubsub     *redis.PubSub
+	stats      counters
+	logger     *slog.Logger
+}
+
+func NewGoodsCache(client redis.UniversalClient, cfg Config, logger *slog.Logger) *GoodsCache {
+	c := &GoodsCache{
+		client:     client,
+		cfg:        cfg,
+		logger:     logger,
+		breaker:    newBreaker(breakerThreshold, breakerCooldown),
+		instanceID: uuid.NewString(),
+	}
+	if cfg.LocalSize > 0 {
+		c.local = newLRU(cfg.LocalSize, cfg.LocalTTL)
+	}
+	return c
 }
 
-func NewGoodsCache(client *redis.Client) *GoodsCache {
-	return &GoodsCache{client: client}
+// Start подписывает кэш процесса на удаления ключей другими репликами
+func (c *GoodsCache) Start() {
+	if c.local == nil {
+		return
+	}
+
+	c.pubsub = c.client.Subscribe(context.Background(), invalidationChannel)
+	go func() {
+		for msg := range c.pubsub.Channel() {
+			// Сообщение содержит ID реплики-отправителя и удалённые ключи через пробел
+			fields := strings.Fields(msg.Payload)
+			if len(fields) < 2 || fields[0] == c.instanceID {
+				continue
+			}
+			for _, key := range fields[1:] {
+				c.local.Delete(key)
+			}
+		}
+	}()
+}
+
+// Close отписывается от удалений ключей другими репликами
+func (c *GoodsCache) Close() error {
+	if c.pubsub == nil {
+		return nil
+	}
+	return c.pubsub.Close()
+}
+
+// Stats возвращает счётчики попаданий и промахов по уровням кэша
+func (c *GoodsCache) Stats() Stats {
+	return c.stats.snapshot()
+}
+
+// Degraded сообщает, что кэш временно не обращается к Redis
+func (c *GoodsCache) Degraded() bool {
+	return c.breaker.Open()
+}
+
+// Ping проверяет доступность Redis и передаёт результат в breaker, поэтому
+// проверка готовности выводит кэш из деградированного режима, даже если
+// запросов к товарам нет.
+func (c *GoodsCache) Ping(ctx context.Context) error {
+	err := c.client.Ping(ctx).Err()
+	c.record(err)
+	return err
 }
 
 func (c *GoodsCache) Set(ctx context.Context, key string, good *models.Good) error {
-	data, err := json.Marshal(good)
+	data, err := encodeGood(good)
+	if err != nil {
+		return fmt.Errorf("encode good: %w", err)
+	}
+
+	if c.local != nil {
+		c.local.Set(key, good)
+	}
+
+	return c.set(ctx, key, data, c.ttl())
+}
+
+func (c *GoodsCache) Get(ctx context.Context, key string) (*models.Good, error) {
+	good, _, err := c.lookup(ctx, key)
+	return good, err
+}
+
+// GetOrLoad возвращает товар из кэша, а при промахе загружает его через load.
+// Одновременные промахи по одному ключу выполняют одну загрузку, а отсутствие
+// товара запоминается на NegativeTTL. Для отсутствующего товара возвращает nil, nil.
+func (c *GoodsCache) GetOrLoad(ctx context.Context, key string, load LoadFunc) (*models.Good, error) {
+	return c.getOrLoad(ctx, key, key, true, load)
+}
+
+// GetOrLoadAs работает как GetOrLoad, но load видит товары только проектов
+// субъекта subject. Загрузка делится лишь между запросами этого субъекта,
+// а nil не запоминается: товар может существовать в другом проекте.
+func (c *GoodsCache) GetOrLoadAs(ctx context.Context, key, subject string, load LoadFunc) (*models.Good, error) {
+	return c.getOrLoad(ctx, key, key+"@"+subject, false, load)
+}
+
+// getOrLoad выполняет загрузки с одинаковым flight один раз. tombstone
+// разрешает запомнить отсутствие товара.
+func (c *GoodsCache) getOrLoad(ctx context.Context, key, flight string, tombstone bool, load LoadFunc) (*models.Good, error) {
+	good, found, err := c.lookup(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		return good, nil
+	}
+
+	result, err, _ := c.loads.Do(flight, func() (interface{}, error) {
+		// Загрузка не прерывается отменой запроса, который её начал:
+		// её результата ждут и другие запросы
+		loadCtx := context.WithoutCancel(ctx)
+
+		good, err := load(loadCtx)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case good != nil:
+			err = c.Set(loadCtx, key, good)
+		case tombstone:
+			err = c.setTombstone(loadCtx, key)
+		}
+		if err != nil {
+			c.logger.WarnContext(ctx, "cache good", "key", key, "error", err)
+		}
+
+		return good, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*models.Good), nil
+}
+
+// Delete удаляет товары из кэша на всех репликах за один обмен с Redis.
+// В деградированном режиме возвращает ErrUnavailable: записи останутся
+// в Redis до истечения TTL.
+func (c *GoodsCache) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	if c.local != nil {
+		for _, key := range keys {
+			c.local.Delete(key)
+		}
+	}
+
+	if !c.breaker.Allow() {
+		return ErrUnavailable
+	}
+
+	// Ключи удаляются по одному в конвейере: в кластере они могут
+	// принадлежать разным слотам
+	_, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.Del(ctx, key)
+		}
+		return nil
+	})
+	c.record(err)
+	if err != nil {
+		return fmt.Errorf("delete cache: %w", err)
+	}
+
+	return c.publishInvalidation(ctx, keys)
+}
+
+// Refresh обновляет кэш после изменения товаров. В режиме write-through
+// новые значения записываются в кэш, иначе ключи удаляются.
+func (c *GoodsCache) Refresh(ctx context.Context, goods ...*models.Good) error {
+	keys := make([]string, len(goods))
+	for i, good := range goods {
+		keys[i] = GoodKey(good.ID)
+	}
+
+	if !c.cfg.WriteThrough {
+		return c.Delete(ctx, keys...)
+	}
+
+	if err := c.setMany(ctx, goods); err != nil {
+		return err
+	}
+
+	return c.publishInvalidation(ctx, keys)
+}
+
+// Invalidate обрабатывает изменение товаров проекта, сделанное в обход
+// сервиса: делает недействительными страницы списка проекта и удаляет товары
+// из кэша, а в режиме write-through перечитывает их через load.
+func (c *GoodsCache) Invalidate(ctx context.Context, projectID int64, ids []int64, load ManyLoadFunc) error {
+	if err := c.BumpProject(ctx, projectID); err != nil {
+		return err
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = GoodKey(id)
+	}
+
+	if !c.cfg.WriteThrough {
+		return c.Delete(ctx, keys...)
+	}
+
+	goods, err := load(ctx, ids)
 	if err != nil {
-		return fmt.Errorf("marshal good: %w", err)
+		return fmt.Errorf("load goods: %w", err)
+	}
+
+	// Товары, которых больше нет, удаляются из кэша
+	found := make(map[int64]bool, len(goods))
+	for _, good := range goods {
+		found[good.ID] = true
+	}
+	var missing []string
+	for i, id := range ids {
+		if !found[id] {
+			missing = append(missing, keys[i])
+		}
+	}
+	if err := c.Delete(ctx, missing...); err != nil {
+		return err
+	}
+
+	if len(goods) == 0 {
+		return nil
+	}
+	return c.Refresh(ctx, goods...)
+}
+
+// Warm загружает товары в кэш, например при старте сервиса
+func (c *GoodsCache) Warm(ctx context.Context, goods []*models.Good) error {
+	return c.setMany(ctx, goods)
+}
+
+// setMany записывает товары в кэш за один обмен с Redis
+func (c *GoodsCache) setMany(ctx context.Context, goods []*models.Good) error {
+	if len(goods) == 0 {
+		return nil
 	}
 
-	err = c.client.Set(ctx, key, data, time.Minute).Err()
+	if c.local != nil {
+		for _, good := range goods {
+			c.local.Set(GoodKey(good.ID), good)
+		}
+	}
+
+	if !c.breaker.Allow() {
+		return ErrUnavailable
+	}
+
+	_, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, good := range goods {
+			data, err := encodeGood(good)
+			if err != nil {
+				return fmt.Errorf("encode good: %w", err)
+			}
+			pipe.Set(ctx, GoodKey(good.ID), data, c.ttl())
+		}
+		return nil
+	})
+	c.record(err)
 	if err != nil {
 		return fmt.Errorf("set cache: %w", err)
 	}
@@ -32,32 +349,144 @@ func (c *GoodsCache) Set(ctx context.Context, key string, good *models.Good) err
 	return nil
 }
 
-func (c *GoodsCache) Get(ctx context.Context, key string) (*models.Good, error) {
+// publishInvalidation сообщает другим репликам, что ключи нужно убрать
+// из их кэшей процесса
+func (c *GoodsCache) publishInvalidation(ctx context.Context, keys []string) error {
+	if c.local == nil {
+		return nil
+	}
+
+	payload := c.instanceID + " " + strings.Join(keys, " ")
+	if err := c.client.Publish(ctx, invalidationChannel, payload).Err(); err != nil {
+		return fmt.Errorf("publish invalidation: %w", err)
+	}
+
+	return nil
+}
+
+// lookup ищет запись сначала в кэше процесса, затем в Redis
+func (c *GoodsCache) lookup(ctx context.Context, key string) (*models.Good, bool, error) {
+	if c.local != nil {
+		if good, ok := c.local.Get(key); ok {
+			c.stats.localHits.Add(1)
+			return good, true, nil
+		}
+		c.stats.localMisses.Add(1)
+	}
+
+	good, found, err := c.get(ctx, key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if !found {
+		c.stats.redisMisses.Add(1)
+		return nil, false, nil
+	}
+
+	c.stats.redisHits.Add(1)
+	if c.local != nil {
+		c.local.Set(key, good)
+	}
+	return good, true, nil
+}
+
+// get читает запись из кэша. found сообщает, что запись есть в кэше:
+// товар или отметка о его отсутствии (тогда good равен nil).
+func (c *GoodsCache) get(ctx context.Context, key string) (good *models.Good, found bool, err error) {
+	data, ok := c.getRaw(ctx, key)
+	if !ok {
+		return nil, false, nil
+	}
+
+	good, err = decodeGood(data)
+	if errors.Is(err, errStale) {
+		// Запись сохранена до изменения формата или структуры товара
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("decode good: %w", err)
+	}
+
+	return good, true, nil
+}
+
+// setTombstone запоминает отсутствие товара на NegativeTTL
+func (c *GoodsCache) setTombstone(ctx context.Context, key string) error {
+	if c.local != nil {
+		c.local.Set(key, nil)
+	}
+
+	data, err := encodeGood(nil)
+	if err != nil {
+		return fmt.Errorf("encode good: %w", err)
+	}
+	return c.set(ctx, key, data, c.cfg.NegativeTTL)
+}
+
+// getRaw читает значение из Redis. Ошибки считаются промахом.
+func (c *GoodsCache) getRaw(ctx context.Context, key string) ([]byte, bool) {
+	if !c.breaker.Allow() {
+		return nil, false
+	}
+
 	data, err := c.client.Get(ctx, key).Bytes()
 	if err != nil {
 		if err == redis.Nil {
-			return nil, nil
+			c.record(nil)
+			return nil, false
 		}
-		return nil, fmt.Errorf("get cache: %w", err)
+		c.record(err)
+		c.logger.WarnContext(ctx, "read cache, treating as miss", "key", key, "error", err)
+		return nil, false
 	}
+	c.record(nil)
 
-	var good models.Good
-	if err := json.Unmarshal(data, &good); err != nil {
-		return nil, fmt.Errorf("unmarshal good: %w", err)
-	}
-
-	return &good, nil
+	return data, true
 }
 
-func (c *GoodsCache) Delete(ctx context.Context, key string) error {
-	err := c.client.Del(ctx, key).Err()
+func (c *GoodsCache) set(ctx context.Context, key string, data []byte, ttl time.Duration) error {
+	if !c.breaker.Allow() {
+		return nil
+	}
+
+	err := c.client.Set(ctx, key, data, ttl).Err()
+	c.record(err)
 	if err != nil {
-		return fmt.Errorf("delete cache: %w", err)
+		return fmt.Errorf("set cache: %w", err)
 	}
 
 	return nil
 }
 
+// ttl возвращает время жизни записи со случайной добавкой
+func (c *GoodsCache) ttl() time.Duration {
+	if c.cfg.TTLJitter <= 0 {
+		return c.cfg.TTL
+	}
+	return c.cfg.TTL + rand.N(c.cfg.TTLJitter)
+}
+
+// record передаёт результат обращения к Redis в breaker
+func (c *GoodsCache) record(err error) {
+	// Отмена запроса клиентом не говорит о состоянии Redis
+	if errors.Is(err, context.Canceled) {
+		c.breaker.Abort()
+		return
+	}
+
+	if err == nil {
+		if c.breaker.Success() {
+			c.logger.Info("redis is available again, leaving degraded mode")
+		}
+		return
+	}
+
+	if c.breaker.Failure() {
+		c.logger.Error("redis is unavailable, entering degraded mode", "cooldown", breakerCooldown, "error", err)
+	}
+}
+
 func GoodKey(id int64) string {
 	return fmt.Sprintf("good:%d", id)
 }
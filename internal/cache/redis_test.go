@@ -0,0 +1,343 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/yangirxd/goods-service/internal/models"
+)
+
+// testLogger отбрасывает журнал кэша
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func newTestCache(t *testing.T) (*GoodsCache, *miniredis.Miniredis) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return NewGoodsCache(client, DefaultConfig(), testLogger), server
+}
+
+func TestSetGet(t *testing.T) {
+	c, _ := newTestCache(t)
+	ctx := context.Background()
+
+	if err := c.Set(ctx, GoodKey(1), &models.Good{ID: 1, ProjectID: 2, Name: "a"}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	good, err := c.Get(ctx, GoodKey(1))
+	if err != nil || good == nil || good.ID != 1 || good.Name != "a" {
+		t.Errorf("Get() = %+v, %v, want the stored good", good, err)
+	}
+
+	good, err = c.Get(ctx, GoodKey(2))
+	if err != nil || good != nil {
+		t.Errorf("Get() = %+v, %v for a missing key, want nil, nil", good, err)
+	}
+}
+
+func TestRedisErrorsAreMisses(t *testing.T) {
+	c, server := newTestCache(t)
+	ctx := context.Background()
+	server.SetError("LOADING Redis is loading the dataset in memory")
+
+	for i := 0; i < breakerThreshold; i++ {
+		good, err := c.Get(ctx, GoodKey(1))
+		if err != nil || good != nil {
+			t.Fatalf("Get() = %+v, %v, want a miss", good, err)
+		}
+	}
+
+	if !c.Degraded() {
+		t.Fatal("Degraded() = false after repeated Redis errors")
+	}
+
+	// В деградированном режиме Redis не вызывается
+	server.SetError("")
+	if err := c.Set(ctx, GoodKey(1), &models.Good{ID: 1}); err != nil {
+		t.Errorf("Set() error = %v in degraded mode", err)
+	}
+	if server.Exists(GoodKey(1)) {
+		t.Error("Set() wrote to Redis in degraded mode")
+	}
+	if err := c.Delete(ctx, GoodKey(1)); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Delete() error = %v, want ErrUnavailable", err)
+	}
+}
+
+func TestGetOrLoadCoalescesMisses(t *testing.T) {
+	c, _ := newTestCache(t)
+	ctx := context.Background()
+
+	var loads atomic.Int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	load := func(context.Context) (*models.Good, error) {
+		if loads.Add(1) == 1 {
+			close(started)
+		}
+		<-release
+		return &models.Good{ID: 1}, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			good, err := c.GetOrLoad(ctx, GoodKey(1), load)
+			if err != nil || good == nil || good.ID != 1 {
+				t.Errorf("GetOrLoad() = %+v, %v, want the loaded good", good, err)
+			}
+		}()
+	}
+
+	<-started
+	// Даём остальным запросам дойти до ожидания загрузки
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := loads.Load(); n != 1 {
+		t.Errorf("load called %d times, want 1", n)
+	}
+}
+
+func TestGetOrLoadRemembersMissingGoods(t *testing.T) {
+	c, server := newTestCache(t)
+	ctx := context.Background()
+	key := GoodKey(1)
+
+	if _, err := c.GetOrLoad(ctx, key, func(context.Context) (*models.Good, error) { return nil, nil }); err != nil {
+		t.Fatalf("GetOrLoad() error = %v", err)
+	}
+	if ttl := server.TTL(key); ttl <= 0 || ttl > DefaultConfig().NegativeTTL {
+		t.Errorf("tombstone TTL = %v, want up to %v", ttl, DefaultConfig().NegativeTTL)
+	}
+
+	good, err := c.GetOrLoad(ctx, key, func(context.Context) (*models.Good, error) {
+		t.Error("load called for a remembered missing good")
+		return nil, nil
+	})
+	if err != nil || good != nil {
+		t.Errorf("GetOrLoad() = %+v, %v, want nil, nil", good, err)
+	}
+}
+
+func TestGetOrLoadAsDoesNotRememberMissingGoods(t *testing.T) {
+	c, _ := newTestCache(t)
+	ctx := context.Background()
+	key := GoodKey(1)
+
+	// Субъекту товар не виден, но это не значит, что его нет
+	good, err := c.GetOrLoadAs(ctx, key, "alice", func(context.Context) (*models.Good, error) {
+		return nil, nil
+	})
+	if err != nil || good != nil {
+		t.Fatalf("GetOrLoadAs() = %v, %v, want nil, nil", good, err)
+	}
+
+	loads := 0
+	good, err = c.GetOrLoad(ctx, key, func(context.Context) (*models.Good, error) {
+		loads++
+		return &models.Good{ID: 1, ProjectID: 2}, nil
+	})
+	if err != nil {
+		t.Fatalf("GetOrLoad() error = %v", err)
+	}
+	if loads != 1 || good == nil || good.ID != 1 {
+		t.Errorf("GetOrLoad() = %+v after %d loads, want the loaded good", good, loads)
+	}
+}
+
+func TestGetOrLoadAsCachesVisibleGoods(t *testing.T) {
+	c, _ := newTestCache(t)
+	ctx := context.Background()
+	key := GoodKey(1)
+
+	_, err := c.GetOrLoadAs(ctx, key, "alice", func(context.Context) (*models.Good, error) {
+		return &models.Good{ID: 1, ProjectID: 2}, nil
+	})
+	if err != nil {
+		t.Fatalf("GetOrLoadAs() error = %v", err)
+	}
+
+	good, err := c.GetOrLoadAs(ctx, key, "bob", func(context.Context) (*models.Good, error) {
+		t.Error("load called for a cached good")
+		return nil, nil
+	})
+	if err != nil || good == nil || good.ProjectID != 2 {
+		t.Errorf("GetOrLoadAs() = %+v, %v, want the cached good", good, err)
+	}
+}
+
+func TestSetTTLJitter(t *testing.T) {
+	c, server := newTestCache(t)
+	cfg := DefaultConfig()
+
+	if err := c.Set(context.Background(), GoodKey(1), &models.Good{ID: 1}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if ttl := server.TTL(GoodKey(1)); ttl < cfg.TTL || ttl >= cfg.TTL+cfg.TTLJitter {
+		t.Errorf("TTL = %v, want in [%v, %v)", ttl, cfg.TTL, cfg.TTL+cfg.TTLJitter)
+	}
+}
+
+func TestLocalCacheInvalidation(t *testing.T) {
+	server := miniredis.RunT(t)
+	cfg := DefaultConfig()
+	cfg.LocalSize = 10
+	cfg.LocalTTL = time.Hour
+
+	newReplica := func() *GoodsCache {
+		client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		c := NewGoodsCache(client, cfg, testLogger)
+		c.Start()
+		t.Cleanup(func() {
+			c.Close()
+			client.Close()
+		})
+		return c
+	}
+	a, b := newReplica(), newReplica()
+	ctx := context.Background()
+	key := GoodKey(1)
+
+	if err := a.Set(ctx, key, &models.Good{ID: 1}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if good, err := b.Get(ctx, key); err != nil || good == nil {
+		t.Fatalf("Get() = %+v, %v, want the stored good", good, err)
+	}
+	if good, err := b.Get(ctx, key); err != nil || good == nil {
+		t.Fatalf("Get() = %+v, %v, want the stored good", good, err)
+	}
+	if stats := b.Stats(); stats.LocalHits != 1 || stats.RedisHits != 1 {
+		t.Errorf("Stats() = %+v, want one local and one Redis hit", stats)
+	}
+
+	// Ждём, пока обе реплики подпишутся на удаления ключей
+	deadline := time.Now().Add(time.Second)
+	for server.PubSubNumSub(invalidationChannel)[invalidationChannel] < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("replicas did not subscribe to invalidations")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := a.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	deadline = time.Now().Add(time.Second)
+	for {
+		if _, ok := b.local.Get(key); !ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("key was not evicted from the other replica")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	tests := []struct {
+		name         string
+		writeThrough bool
+	}{
+		{"delete", false},
+		{"write-through", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := miniredis.RunT(t)
+			client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+			t.Cleanup(func() { client.Close() })
+			cfg := DefaultConfig()
+			cfg.WriteThrough = tt.writeThrough
+			c := NewGoodsCache(client, cfg, testLogger)
+			ctx := context.Background()
+
+			if err := c.Set(ctx, GoodKey(1), &models.Good{ID: 1, Name: "old"}); err != nil {
+				t.Fatalf("Set() error = %v", err)
+			}
+			if err := c.Refresh(ctx, &models.Good{ID: 1, Name: "new"}, &models.Good{ID: 2, Name: "new"}); err != nil {
+				t.Fatalf("Refresh() error = %v", err)
+			}
+
+			for _, id := range []int64{1, 2} {
+				good, err := c.Get(ctx, GoodKey(id))
+				if err != nil {
+					t.Fatalf("Get() error = %v", err)
+				}
+				if tt.writeThrough && (good == nil || good.Name != "new") {
+					t.Errorf("Get(%d) = %+v, want the refreshed good", id, good)
+				}
+				if !tt.writeThrough && good != nil {
+					t.Errorf("Get(%d) = %+v, want a miss", id, good)
+				}
+			}
+		})
+	}
+}
+
+func TestWarm(t *testing.T) {
+	c, server := newTestCache(t)
+	ctx := context.Background()
+
+	goods := []*models.Good{{ID: 1}, {ID: 2}}
+	if err := c.Warm(ctx, goods); err != nil {
+		t.Fatalf("Warm() error = %v", err)
+	}
+	for _, good := range goods {
+		if ttl := server.TTL(GoodKey(good.ID)); ttl < DefaultConfig().TTL {
+			t.Errorf("TTL of %s = %v, want at least %v", GoodKey(good.ID), ttl, DefaultConfig().TTL)
+		}
+	}
+}
+
+func TestInvalidateWriteThrough(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	cfg := DefaultConfig()
+	cfg.WriteThrough = true
+	c := NewGoodsCache(client, cfg, testLogger)
+	ctx := context.Background()
+
+	for _, id := range []int64{1, 2} {
+		if err := c.Set(ctx, GoodKey(id), &models.Good{ID: id, Name: "old"}); err != nil {
+			t.Fatalf("Set() error = %v", err)
+		}
+	}
+
+	// Товар 2 удалён из базы в обход сервиса
+	err := c.Invalidate(ctx, 7, []int64{1, 2}, func(_ context.Context, ids []int64) ([]*models.Good, error) {
+		return []*models.Good{{ID: 1, ProjectID: 7, Name: "new"}}, nil
+	})
+	if err != nil {
+		t.Fatalf("Invalidate() error = %v", err)
+	}
+
+	if good, err := c.Get(ctx, GoodKey(1)); err != nil || good == nil || good.Name != "new" {
+		t.Errorf("Get(1) = %+v, %v, want the reloaded good", good, err)
+	}
+	if server.Exists(GoodKey(2)) {
+		t.Error("removed good is still cached")
+	}
+	if gen, _ := server.Get(projectGenerationKey(7)); gen != "1" {
+		t.Errorf("project generation = %q, want 1", gen)
+	}
+}
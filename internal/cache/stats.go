@@ -0,0 +1,27 @@
+package cache
+
+import "sync/atomic"
+
+// Stats содержит количество попаданий и промахов по уровням кэша
+type Stats struct {
+	LocalHits   uint64 `json:"local_hits"`
+	LocalMisses uint64 `json:"local_misses"`
+	RedisHits   uint64 `json:"redis_hits"`
+	RedisMisses uint64 `json:"redis_misses"`
+}
+
+type counters struct {
+	localHits   atomic.Uint64
+	localMisses atomic.Uint64
+	redisHits   atomic.Uint64
+	redisMisses atomic.Uint64
+}
+
+func (c *counters) snapshot() Stats {
+	return Stats{
+		LocalHits:   c.localHits.Load(),
+		LocalMisses: c.localMisses.Load(),
+		RedisHits:   c.redisHits.Load(),
+		RedisMisses: c.redisMisses.Load(),
+	}
+}
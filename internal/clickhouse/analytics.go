@@ -0,0 +1,165 @@
+package clickhouse
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yangirxd/goods-service/internal/models"
+)
+
+const dateLayout = "2006-01-02"
+
+// AnalyticsFilter задаёт период и проекты для агрегированной статистики.
+// Границы периода включаются. nil вместо ProjectIDs означает все проекты,
+// пустой список — ни одного.
+type AnalyticsFilter struct {
+	ProjectIDs []int64
+	From       time.Time
+	To         time.Time
+	Limit      int
+}
+
+// where возвращает условие выборки по периоду и проектам
+func (f AnalyticsFilter) where() (string, []interface{}) {
+	where := "event_date >= toDate(?) AND event_date <= toDate(?)"
+	args := []interface{}{f.From.Format(dateLayout), f.To.Format(dateLayout)}
+	switch {
+	case f.ProjectIDs == nil:
+	case len(f.ProjectIDs) == 0:
+		where += " AND 0"
+	default:
+		placeholders := make([]string, len(f.ProjectIDs))
+		for i, id := range f.ProjectIDs {
+			placeholders[i] = "?"
+			args = append(args, id)
+		}
+		where += " AND project_id IN (" + strings.Join(placeholders, ", ") + ")"
+	}
+	return where, args
+}
+
+// dailyActions подзапрос с числом разных событий каждого типа по проектам
+// и дням. Агрегаты хранят состояния uniqExact по event_id, поэтому копии
+// одного события считаются один раз.
+const dailyActions = `
+		SELECT
+			event_date,
+			project_id,
+			action,
+			uniqExactMerge(events) AS events
+		FROM logs.goods_daily_actions
+		WHERE %s
+		GROUP BY event_date, project_id, action`
+
+// DailyStats возвращает количество событий каждого типа по проектам и дням
+func (c *Client) DailyStats(ctx context.Context, filter AnalyticsFilter) ([]models.DailyStats, error) {
+	where, args := filter.where()
+
+	rows, err := c.db.QueryContext(ctx, `
+		SELECT
+			event_date,
+			project_id,
+			sumIf(events, action = 'create'),
+			sumIf(events, action = 'update'),
+			sumIf(events, action = 'delete'),
+			sumIf(events, action = 'reprioritize')
+		FROM (`+fmt.Sprintf(dailyActions, where)+`)
+		GROUP BY event_date, project_id
+		ORDER BY event_date, project_id
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("выборка статистики по дням: %w", err)
+	}
+	defer rows.Close()
+
+	stats := make([]models.DailyStats, 0)
+	for rows.Next() {
+		var s models.DailyStats
+		if err := rows.Scan(&s.Date, &s.ProjectID, &s.Creates, &s.Updates, &s.Deletes, &s.Reprioritizes); err != nil {
+			return nil, fmt.Errorf("чтение статистики по дням: %w", err)
+		}
+		stats = append(stats, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("перебор статистики по дням: %w", err)
+	}
+
+	return stats, nil
+}
+
+// TopReprioritized возвращает товары, приоритет которых менялся чаще всего
+func (c *Client) TopReprioritized(ctx context.Context, filter AnalyticsFilter) ([]models.ReprioritizedGood, error) {
+	where, args := filter.where()
+	args = append(args, filter.Limit)
+
+	rows, err := c.db.QueryContext(ctx, `
+		SELECT
+			entity_id,
+			project_id,
+			uniqExactMerge(reprioritizations) AS total
+		FROM logs.goods_reprioritizations
+		WHERE `+where+`
+		GROUP BY project_id, entity_id
+		ORDER BY total DESC, entity_id
+		LIMIT ?
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("выборка изменений приоритета: %w", err)
+	}
+	defer rows.Close()
+
+	goods := make([]models.ReprioritizedGood, 0, filter.Limit)
+	for rows.Next() {
+		var g models.ReprioritizedGood
+		if err := rows.Scan(&g.GoodID, &g.ProjectID, &g.Reprioritizations); err != nil {
+			return nil, fmt.Errorf("чтение изменений приоритета: %w", err)
+		}
+		goods = append(goods, g)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("перебор изменений приоритета: %w", err)
+	}
+
+	return goods, nil
+}
+
+// Churn возвращает отток товаров по проектам за период
+func (c *Client) Churn(ctx context.Context, filter AnalyticsFilter) ([]models.ChurnStats, error) {
+	where, args := filter.where()
+
+	rows, err := c.db.QueryContext(ctx, `
+		SELECT
+			project_id,
+			sumIf(events, action = 'create'),
+			sumIf(events, action = 'delete')
+		FROM (`+fmt.Sprintf(dailyActions, where)+`)
+		GROUP BY project_id
+		ORDER BY project_id
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("выборка оттока: %w", err)
+	}
+	defer rows.Close()
+
+	stats := make([]models.ChurnStats, 0)
+	for rows.Next() {
+		var s models.ChurnStats
+		if err := rows.Scan(&s.ProjectID, &s.Creates, &s.Deletes); err != nil {
+			return nil, fmt.Errorf("чтение оттока: %w", err)
+		}
+		if s.Creates > 0 {
+			s.ChurnRate = float64(s.Deletes) / float64(s.Creates)
+		}
+		stats = append(stats, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("перебор оттока: %w", err)
+	}
+
+	return stats, nil
+}
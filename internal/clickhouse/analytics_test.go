@@ -0,0 +1,50 @@
+package clickhouse
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAnalyticsFilterWhere(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+	period := "event_date >= toDate(?) AND event_date <= toDate(?)"
+
+	tests := []struct {
+		name       string
+		projectIDs []int64
+		wantWhere  string
+		wantArgs   []interface{}
+	}{
+		{
+			name:      "all projects",
+			wantWhere: period,
+			wantArgs:  []interface{}{"2024-01-01", "2024-01-31"},
+		},
+		{
+			name:       "no projects",
+			projectIDs: []int64{},
+			wantWhere:  period + " AND 0",
+			wantArgs:   []interface{}{"2024-01-01", "2024-01-31"},
+		},
+		{
+			name:       "some projects",
+			projectIDs: []int64{1, 3},
+			wantWhere:  period + " AND project_id IN (?, ?)",
+			wantArgs:   []interface{}{"2024-01-01", "2024-01-31", int64(1), int64(3)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			where, args := AnalyticsFilter{ProjectIDs: tt.projectIDs, From: from, To: to}.where()
+			if where != tt.wantWhere {
+				t.Errorf("where() = %q, want %q", where, tt.wantWhere)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("where() args = %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
@@ -1,35 +1,66 @@
 package clickhouse
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"sync"
 	"time"
 
 	_ "github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/yangirxd/goods-service/internal/metrics"
+	"github.com/yangirxd/goods-service/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
-const batchSize = 100
-const flushInterval = 5 * time.Second
+// Config задаёт подключение к ClickHouse и запись событий пакетами
+type Config struct {
+	URL string `yaml:"url"`
+	// BatchSize количество событий, при котором пакет записывается сразу
+	BatchSize int `yaml:"batch_size"`
+	// FlushInterval период записи неполного пакета
+	FlushInterval time.Duration `yaml:"flush_interval"`
+}
+
+// DefaultConfig возвращает настройки ClickHouse по умолчанию
+func DefaultConfig() Config {
+	return Config{
+		URL:           "tcp://localhost:9000?database=logs",
+		BatchSize:     100,
+		FlushInterval: 5 * time.Second,
+	}
+}
 
 type LogEvent struct {
+	ID        string      `json:"id"`
 	Action    string      `json:"action"`
 	Timestamp time.Time   `json:"timestamp"`
 	EntityID  int64       `json:"entity_id"`
+	ProjectID int64       `json:"project_id"`
+	RequestID string      `json:"request_id"`
+	Actor     string      `json:"actor"`
 	Data      interface{} `json:"data"`
+	// Trace контекст трассировки, в которой событие было получено
+	Trace trace.SpanContext `json:"-"`
 }
 
 type Client struct {
-	db     *sql.DB
-	batch  []*LogEvent
-	mu     sync.Mutex
-	stopCh chan struct{}
+	db       *sql.DB
+	cfg      Config
+	batch    []*LogEvent
+	mu       sync.Mutex
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	logger   *slog.Logger
 }
 
 // NewClient создает новый экземпляр клиента ClickHouse
-func NewClient(clickhouseURL string) (*Client, error) {
-	db, err := sql.Open("clickhouse", clickhouseURL)
+func NewClient(cfg Config, logger *slog.Logger) (*Client, error) {
+	db, err := sql.Open("clickhouse", cfg.URL)
 	if err != nil {
 		return nil, fmt.Errorf("подключение к ClickHouse: %w", err)
 	}
@@ -41,8 +72,10 @@ func NewClient(clickhouseURL string) (*Client, error) {
 
 	return &Client{
 		db:     db,
-		batch:  make([]*LogEvent, 0, batchSize),
+		cfg:    cfg,
+		batch:  make([]*LogEvent, 0, cfg.BatchSize),
 		stopCh: make(chan struct{}),
+		logger: logger,
 	}, nil
 }
 
@@ -53,10 +86,18 @@ func (c *Client) Start() {
 
 // Stop останавливает обработку и записывает оставшиеся логи
 func (c *Client) Stop() error {
-	close(c.stopCh)
+	c.stopOnce.Do(func() { close(c.stopCh) })
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	return c.flush()
 }
 
+// Ping проверяет доступность ClickHouse
+func (c *Client) Ping(ctx context.Context) error {
+	return c.db.PingContext(ctx)
+}
+
 // Close закрывает соединение с ClickHouse
 func (c *Client) Close() error {
 	if err := c.Stop(); err != nil {
@@ -71,7 +112,8 @@ func (c *Client) AddEvent(event *LogEvent) error {
 	defer c.mu.Unlock()
 
 	c.batch = append(c.batch, event)
-	if len(c.batch) >= batchSize {
+	metrics.ClickHousePending.Set(float64(len(c.batch)))
+	if len(c.batch) >= c.cfg.BatchSize {
 		return c.flush()
 	}
 	return nil
@@ -79,7 +121,7 @@ func (c *Client) AddEvent(event *LogEvent) error {
 
 // flushLoop периодически записывает накопленные логи
 func (c *Client) flushLoop() {
-	ticker := time.NewTicker(flushInterval)
+	ticker := time.NewTicker(c.cfg.FlushInterval)
 	defer ticker.Stop()
 
 	for {
@@ -88,7 +130,7 @@ func (c *Client) flushLoop() {
 			c.mu.Lock()
 			if len(c.batch) > 0 {
 				if err := c.flush(); err != nil {
-					fmt.Printf("Ошибка периодической записи: %v\n", err)
+					c.logger.Error("periodic clickhouse flush", "events", len(c.batch), "error", err)
 				}
 			}
 			c.mu.Unlock()
@@ -104,6 +146,64 @@ func (c *Client) flush() error {
 		return nil
 	}
 
+	start := time.Now()
+	err := c.writeBatch()
+	metrics.ClickHouseFlushDuration.WithLabelValues(metrics.Status(err)).Observe(time.Since(start).Seconds())
+	c.traceFlush(start, err)
+	if err != nil {
+		return err
+	}
+
+	metrics.ClickHouseBatchSize.Observe(float64(len(c.batch)))
+	// Очищаем пакет после успешной записи
+	c.batch = c.batch[:0]
+	metrics.ClickHousePending.Set(0)
+	return nil
+}
+
+// traceFlush записывает span записи пакета со ссылками на трассировки
+// событий, а в каждой трассировке события span записи этого события,
+// чтобы запись в ClickHouse была видна в трассировке исходного запроса
+func (c *Client) traceFlush(start time.Time, err error) {
+	links := make([]trace.Link, 0, len(c.batch))
+	for _, event := range c.batch {
+		if event.Trace.IsValid() {
+			links = append(links, trace.Link{SpanContext: event.Trace})
+		}
+	}
+
+	attrs := []attribute.KeyValue{
+		attribute.String("db.system", "clickhouse"),
+		attribute.Int("db.operation.batch.size", len(c.batch)),
+	}
+	_, flushSpan := tracing.Tracer.Start(context.Background(), "clickhouse.flush",
+		trace.WithTimestamp(start), trace.WithLinks(links...), trace.WithAttributes(attrs...))
+	spans := []trace.Span{flushSpan}
+
+	for _, event := range c.batch {
+		if !event.Trace.IsValid() {
+			continue
+		}
+		ctx := trace.ContextWithRemoteSpanContext(context.Background(), event.Trace)
+		_, span := tracing.Tracer.Start(ctx, "clickhouse.flush",
+			trace.WithTimestamp(start),
+			trace.WithLinks(trace.Link{SpanContext: flushSpan.SpanContext()}),
+			trace.WithAttributes(attrs...),
+		)
+		spans = append(spans, span)
+	}
+
+	for _, span := range spans {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
+
+// writeBatch записывает пакет в одной транзакции
+func (c *Client) writeBatch() error {
 	tx, err := c.db.Begin()
 	if err != nil {
 		return fmt.Errorf("начало транзакции: %w", err)
@@ -111,8 +211,8 @@ func (c *Client) flush() error {
 	defer tx.Rollback()
 
 	stmt, err := tx.Prepare(`
-		INSERT INTO logs.goods_events (action, timestamp, entity_id, data)
-		VALUES (?, ?, ?, ?)
+		INSERT INTO logs.goods_events (event_id, action, timestamp, entity_id, project_id, request_id, actor, data)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 	`)
 	if err != nil {
 		return fmt.Errorf("подготовка запроса: %w", err)
@@ -122,14 +222,18 @@ func (c *Client) flush() error {
 	for _, event := range c.batch {
 		data, err := json.Marshal(event.Data)
 		if err != nil {
-			fmt.Printf("Ошибка сериализации данных: %v\n", err)
+			c.logger.Error("marshal event data, skipping event", "event_id", event.ID, "error", err)
 			continue
 		}
 
 		_, err = stmt.Exec(
+			event.ID,
 			event.Action,
 			event.Timestamp,
 			event.EntityID,
+			event.ProjectID,
+			event.RequestID,
+			event.Actor,
 			string(data),
 		)
 		if err != nil {
@@ -141,7 +245,5 @@ func (c *Client) flush() error {
 		return fmt.Errorf("подтверждение транзакции: %w", err)
 	}
 
-	// Очищаем пакет после успешной записи
-	c.batch = c.batch[:0]
 	return nil
 }
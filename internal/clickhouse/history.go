@@ -0,0 +1,101 @@
+package clickhouse
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yangirxd/goods-service/internal/models"
+)
+
+// HistoryFilter задаёт условия выборки событий из журнала.
+// Нулевые значения полей означают отсутствие соответствующего фильтра.
+type HistoryFilter struct {
+	// EntityID товар, события которого нужны, в том числе события
+	// reprioritize других товаров, сдвинувшие его приоритет
+	EntityID  int64
+	ProjectID int64
+	Actions   []string
+	From      time.Time
+	To        time.Time
+	Limit     int
+	Offset    int
+}
+
+// History возвращает события из logs.goods_events, начиная с самых новых
+func (c *Client) History(ctx context.Context, filter HistoryFilter) ([]models.GoodEvent, error) {
+	var (
+		conditions []string
+		args       []interface{}
+	)
+
+	if filter.EntityID != 0 {
+		// Приоритет товара меняется и при изменении приоритета другого
+		// товара проекта; такие события перечисляют его в priorities, а
+		// записанные до CloudEvents — в updated_ids
+		conditions = append(conditions, `(entity_id = ? OR (action = 'reprioritize' AND
+			has(arrayMap(p -> JSONExtractInt(p, 'id'), arrayConcat(
+				JSONExtractArrayRaw(data, 'priorities'), JSONExtractArrayRaw(data, 'updated_ids'))), ?)))`)
+		args = append(args, filter.EntityID, filter.EntityID)
+	}
+	if filter.ProjectID != 0 {
+		conditions = append(conditions, "project_id = ?")
+		args = append(args, filter.ProjectID)
+	}
+	if len(filter.Actions) > 0 {
+		placeholders := make([]string, len(filter.Actions))
+		for i, action := range filter.Actions {
+			placeholders[i] = "?"
+			args = append(args, action)
+		}
+		conditions = append(conditions, "action IN ("+strings.Join(placeholders, ", ")+")")
+	}
+	if !filter.From.IsZero() {
+		conditions = append(conditions, "timestamp >= ?")
+		args = append(args, filter.From)
+	}
+	if !filter.To.IsZero() {
+		conditions = append(conditions, "timestamp < ?")
+		args = append(args, filter.To)
+	}
+
+	query := `
+		SELECT toString(event_id), action, timestamp, entity_id, project_id, request_id, actor, data
+		FROM logs.goods_events FINAL`
+	if len(conditions) > 0 {
+		query += "\n\t\tWHERE " + strings.Join(conditions, " AND ")
+	}
+	query += `
+		ORDER BY timestamp DESC
+		LIMIT ? OFFSET ?`
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := c.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("выборка истории: %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]models.GoodEvent, 0, filter.Limit)
+	for rows.Next() {
+		var (
+			event models.GoodEvent
+			data  string
+		)
+		if err := rows.Scan(&event.ID, &event.Action, &event.Timestamp, &event.GoodID, &event.ProjectID, &event.RequestID, &event.Actor, &data); err != nil {
+			return nil, fmt.Errorf("чтение события: %w", err)
+		}
+		if data == "" {
+			data = "null"
+		}
+		event.Data = []byte(data)
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("перебор событий: %w", err)
+	}
+
+	return events, nil
+}
@@ -0,0 +1,238 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/yangirxd/goods-service/internal/auth"
+	"github.com/yangirxd/goods-service/internal/cache"
+	"github.com/yangirxd/goods-service/internal/clickhouse"
+	"github.com/yangirxd/goods-service/internal/db"
+	"github.com/yangirxd/goods-service/internal/logging"
+	"github.com/yangirxd/goods-service/internal/ratelimit"
+	"github.com/yangirxd/goods-service/internal/tracing"
+	"gopkg.in/yaml.v3"
+)
+
+// Config содержит все настройки сервиса. Значения по умолчанию
+// переопределяются YAML-файлом, а он — переменными окружения.
+type Config struct {
+	HTTP       HTTPConfig        `yaml:"http"`
+	Log        LogConfig         `yaml:"log"`
+	Auth       auth.Config       `yaml:"auth"`
+	RateLimit  ratelimit.Config  `yaml:"rate_limit"`
+	Postgres   db.Config         `yaml:"postgres"`
+	Redis      cache.RedisConfig `yaml:"redis"`
+	Cache      CacheConfig       `yaml:"cache"`
+	NATS       NATSConfig        `yaml:"nats"`
+	ClickHouse clickhouse.Config `yaml:"clickhouse"`
+	Health     HealthConfig      `yaml:"health"`
+	Tracing    tracing.Config    `yaml:"tracing"`
+}
+
+type HTTPConfig struct {
+	Addr string `yaml:"addr"`
+	// ShutdownTimeout общее время на остановку сервиса
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// TrustedProxies адреса и сети прокси, чьему X-Forwarded-For можно
+	// верить при определении IP клиента
+	TrustedProxies []string `yaml:"trusted_proxies"`
+}
+
+type LogConfig struct {
+	Format string `yaml:"format"`
+	Level  string `yaml:"level"`
+}
+
+type CacheConfig struct {
+	cache.Config `yaml:",inline"`
+	// WarmupTopN сколько самых приоритетных товаров каждого проекта
+	// загрузить в кэш при старте, 0 отключает прогрев
+	WarmupTopN int `yaml:"warmup_top_n"`
+}
+
+type NATSConfig struct {
+	URL string `yaml:"url"`
+	// QueueGroup группа подписки потребителей логов
+	QueueGroup string `yaml:"queue_group"`
+	// PublishLegacy дублирует события в queue.LegacySubject
+	PublishLegacy bool `yaml:"publish_legacy"`
+}
+
+type HealthConfig struct {
+	// Critical зависимости, недоступность которых делает сервис неготовым
+	Critical []string      `yaml:"critical"`
+	Timeout  time.Duration `yaml:"timeout"`
+}
+
+// Dependencies зависимости, которые проверяет /readyz
+var Dependencies = []string{"postgres", "redis", "nats", "clickhouse"}
+
+// Default возвращает настройки по умолчанию
+func Default() Config {
+	return Config{
+		HTTP: HTTPConfig{
+			Addr:            ":8080",
+			ShutdownTimeout: 15 * time.Second,
+		},
+		Log: LogConfig{
+			Format: logging.FormatJSON,
+			Level:  "info",
+		},
+		Auth:      auth.DefaultConfig(),
+		RateLimit: ratelimit.DefaultConfig(),
+		Postgres:  db.DefaultConfig(),
+		Redis: cache.RedisConfig{
+			Mode:  cache.ModeStandalone,
+			Addrs: []string{"localhost:6379"},
+		},
+		Cache: CacheConfig{Config: cache.DefaultConfig()},
+		NATS: NATSConfig{
+			URL:        "nats://localhost:4222",
+			QueueGroup: "goods-log-consumer",
+		},
+		ClickHouse: clickhouse.DefaultConfig(),
+		Health: HealthConfig{
+			Critical: []string{"postgres", "nats"},
+			Timeout:  2 * time.Second,
+		},
+		Tracing: tracing.Config{
+			Exporter:    tracing.ExporterNone,
+			File:        "traces.jsonl",
+			SampleRatio: 1,
+		},
+	}
+}
+
+// Load собирает настройки из значений по умолчанию, YAML-файла path
+// (если путь не пустой) и переменных окружения и проверяет их
+func Load(path string) (*Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read config file: %w", err)
+		}
+
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&cfg); err != nil {
+			return nil, fmt.Errorf("parse config file %s: %w", path, err)
+		}
+	}
+
+	// Ошибки разбора переменных окружения и проверки выводятся вместе,
+	// чтобы все проблемы были видны с первого запуска
+	if err := errors.Join(cfg.loadEnv(), cfg.Validate()); err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
+
+// Validate проверяет настройки и возвращает все найденные ошибки сразу
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, key, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+		}
+	}
+
+	check(c.HTTP.Addr != "", "HTTP_ADDR", "must not be empty")
+	check(c.HTTP.ShutdownTimeout > 0, "SHUTDOWN_TIMEOUT", "must be positive")
+	for _, proxy := range c.HTTP.TrustedProxies {
+		_, _, cidrErr := net.ParseCIDR(proxy)
+		check(cidrErr == nil || net.ParseIP(proxy) != nil, "HTTP_TRUSTED_PROXIES", "%q is neither an IP address nor a CIDR", proxy)
+	}
+
+	check(c.Log.Format == logging.FormatJSON || c.Log.Format == logging.FormatText,
+		"LOG_FORMAT", "must be %s or %s, got %q", logging.FormatJSON, logging.FormatText, c.Log.Format)
+	if _, err := logging.ParseLevel(c.Log.Level); err != nil {
+		check(false, "LOG_LEVEL", "must be debug, info, warn or error, got %q", c.Log.Level)
+	}
+
+	// HS256 требует ключ не короче выхода хеш-функции (RFC 7518, 3.2)
+	check(c.Auth.Secret == "" || len(c.Auth.Secret) >= 32, "AUTH_JWT_SECRET", "must be at least 32 bytes long")
+	check(c.Auth.JWKSFile == "" || c.Auth.JWKSURL == "", "AUTH_JWKS_URL", "must not be set together with AUTH_JWKS_FILE")
+	check(c.Auth.JWKSRefresh >= 0, "AUTH_JWKS_REFRESH", "must not be negative")
+	check(c.Auth.Leeway >= 0, "AUTH_LEEWAY", "must not be negative")
+	check(!strings.ContainsAny(c.Auth.AdminScope, " \t\n"), "AUTH_ADMIN_SCOPE", "must be a single scope")
+
+	check(c.RateLimit.Default.Rate >= 0, "RATE_LIMIT_RATE", "must not be negative")
+	check(c.RateLimit.Default.Rate == 0 || c.RateLimit.Default.Burst > 0, "RATE_LIMIT_BURST", "must be positive when RATE_LIMIT_RATE is set")
+	check(c.RateLimit.Timeout > 0, "RATE_LIMIT_TIMEOUT", "must be positive")
+	for route, limit := range c.RateLimit.Routes {
+		method, path, ok := strings.Cut(route, " ")
+		check(ok && method != "" && strings.HasPrefix(path, "/"), "RATE_LIMIT_ROUTES", "route %q must look like \"PATCH /goods/reprioritize\"", route)
+		check(limit.Rate >= 0, "RATE_LIMIT_ROUTES", "rate of %q must not be negative", route)
+		check(limit.Rate == 0 || limit.Burst > 0, "RATE_LIMIT_ROUTES", "burst of %q must be positive", route)
+	}
+
+	check(c.Postgres.DSN != "", "POSTGRES_DSN", "must not be empty")
+	check(c.Postgres.MaxOpenConns > 0, "POSTGRES_MAX_OPEN_CONNS", "must be positive")
+	check(c.Postgres.MaxIdleConns >= 0 && c.Postgres.MaxIdleConns <= c.Postgres.MaxOpenConns,
+		"POSTGRES_MAX_IDLE_CONNS", "must be between 0 and POSTGRES_MAX_OPEN_CONNS (%d)", c.Postgres.MaxOpenConns)
+	check(c.Postgres.ConnMaxLifetime >= 0, "POSTGRES_CONN_MAX_LIFETIME", "must not be negative")
+	check(c.Postgres.ConnectTimeout > 0, "POSTGRES_CONNECT_TIMEOUT", "must be positive")
+
+	switch c.Redis.Mode {
+	case cache.ModeStandalone, cache.ModeSentinel, cache.ModeCluster:
+	default:
+		check(false, "REDIS_MODE", "must be %s, %s or %s, got %q",
+			cache.ModeStandalone, cache.ModeSentinel, cache.ModeCluster, c.Redis.Mode)
+	}
+	check(c.Redis.URL != "" || len(c.Redis.Addrs) > 0, "REDIS_ADDR", "must not be empty when REDIS_URL is not set")
+	check(c.Redis.Mode != cache.ModeSentinel || c.Redis.MasterName != "",
+		"REDIS_MASTER_NAME", "must be set in sentinel mode")
+	check(c.Redis.PoolSize >= 0, "REDIS_POOL_SIZE", "must not be negative")
+	check(c.Redis.MinIdleConns >= 0, "REDIS_MIN_IDLE_CONNS", "must not be negative")
+
+	check(c.Cache.TTL > 0, "CACHE_TTL", "must be positive")
+	check(c.Cache.TTLJitter >= 0, "CACHE_TTL_JITTER", "must not be negative")
+	check(c.Cache.NegativeTTL > 0, "CACHE_NEGATIVE_TTL", "must be positive")
+	check(c.Cache.LocalSize >= 0, "CACHE_LOCAL_SIZE", "must not be negative")
+	check(c.Cache.LocalSize == 0 || c.Cache.LocalTTL > 0, "CACHE_LOCAL_TTL", "must be positive when CACHE_LOCAL_SIZE is set")
+	check(c.Cache.WarmupTopN >= 0, "CACHE_WARMUP_TOP_N", "must not be negative")
+
+	check(c.NATS.URL != "", "NATS_URL", "must not be empty")
+	check(c.NATS.QueueGroup != "", "NATS_QUEUE_GROUP", "must not be empty")
+
+	check(c.ClickHouse.URL != "", "CLICKHOUSE_URL", "must not be empty")
+	check(c.ClickHouse.BatchSize > 0, "CLICKHOUSE_BATCH_SIZE", "must be positive")
+	check(c.ClickHouse.FlushInterval > 0, "CLICKHOUSE_FLUSH_INTERVAL", "must be positive")
+
+	for _, name := range c.Health.Critical {
+		check(contains(Dependencies, name), "HEALTH_CRITICAL", "unknown dependency %q, expected one of %v", name, Dependencies)
+	}
+	check(c.Health.Timeout > 0, "HEALTH_TIMEOUT", "must be positive")
+
+	switch c.Tracing.Exporter {
+	case tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterFile, tracing.ExporterOTLP:
+	default:
+		check(false, "TRACING_EXPORTER", "must be %s, %s, %s or %s, got %q",
+			tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterFile, tracing.ExporterOTLP, c.Tracing.Exporter)
+	}
+	check(c.Tracing.Exporter != tracing.ExporterFile || c.Tracing.File != "", "TRACING_FILE", "must be set for the file exporter")
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "TRACING_SAMPLE_RATIO", "must be between 0 and 1")
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
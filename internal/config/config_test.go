@@ -0,0 +1,92 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	want := Default()
+	if cfg.HTTP.Addr != want.HTTP.Addr {
+		t.Errorf("HTTP.Addr = %q, want %q", cfg.HTTP.Addr, want.HTTP.Addr)
+	}
+	if cfg.Cache.TTL != want.Cache.TTL {
+		t.Errorf("Cache.TTL = %v, want %v", cfg.Cache.TTL, want.Cache.TTL)
+	}
+}
+
+func TestLoadLayers(t *testing.T) {
+	path := writeConfig(t, `
+http:
+  addr: ":9090"
+cache:
+  ttl: 5m
+  warmup_top_n: 10
+`)
+	// Переменная окружения важнее файла
+	t.Setenv("CACHE_TTL", "30s")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if cfg.HTTP.Addr != ":9090" {
+		t.Errorf("HTTP.Addr = %q, want %q", cfg.HTTP.Addr, ":9090")
+	}
+	if cfg.Cache.WarmupTopN != 10 {
+		t.Errorf("Cache.WarmupTopN = %d, want 10", cfg.Cache.WarmupTopN)
+	}
+	if cfg.Cache.TTL != 30*time.Second {
+		t.Errorf("Cache.TTL = %v, want 30s", cfg.Cache.TTL)
+	}
+	// Не заданные в файле значения остаются по умолчанию
+	if cfg.NATS.URL != Default().NATS.URL {
+		t.Errorf("NATS.URL = %q, want the default", cfg.NATS.URL)
+	}
+}
+
+func TestLoadRejectsUnknownKeys(t *testing.T) {
+	path := writeConfig(t, `
+http:
+  adr: ":9090"
+`)
+
+	if _, err := Load(path); err == nil {
+		t.Fatal("Load() error = nil, want an error for an unknown key")
+	}
+}
+
+func TestLoadReportsAllErrors(t *testing.T) {
+	t.Setenv("CACHE_TTL", "soon")
+	t.Setenv("LOG_FORMAT", "xml")
+	t.Setenv("HEALTH_CRITICAL", "postgres,kafka")
+
+	_, err := Load("")
+	if err == nil {
+		t.Fatal("Load() error = nil, want validation errors")
+	}
+	for _, key := range []string{"CACHE_TTL", "LOG_FORMAT", "HEALTH_CRITICAL"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("Load() error = %q, want it to mention %s", err, key)
+		}
+	}
+}
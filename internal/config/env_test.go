@@ -0,0 +1,60 @@
+package config
+
+import "testing"
+
+func TestRedactURL(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{
+			name: "url with password",
+			raw:  "postgres://app:hunter2@db:5432/goods?sslmode=disable",
+			want: "postgres://app:REDACTED@db:5432/goods?sslmode=disable",
+		},
+		{
+			name: "url without password",
+			raw:  "redis://db:6379/0",
+			want: "redis://db:6379/0",
+		},
+		{
+			name: "url with password in query",
+			raw:  "clickhouse://db:9000/logs?password=hunter2&username=app",
+			want: "clickhouse://db:9000/logs?password=REDACTED&username=app",
+		},
+		{
+			name: "key value",
+			raw:  "host=db user=app password=hunter2 dbname=goods",
+			want: "host=db user=app password=REDACTED dbname=goods",
+		},
+		{
+			name: "key value with spaces and quotes",
+			raw:  `host=db password = 'hun ter\'2' sslmode=disable`,
+			want: "host=db password = REDACTED sslmode=disable",
+		},
+		{
+			name: "key value with ssl password",
+			raw:  "host=db sslpassword=hunter2 PASSWORD=hunter2",
+			want: "host=db sslpassword=REDACTED PASSWORD=REDACTED",
+		},
+		{
+			name: "key value without password",
+			raw:  "host=db user=app",
+			want: "host=db user=app",
+		},
+		{
+			name: "empty",
+			raw:  "",
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := redactURL(tt.raw); got != tt.want {
+				t.Errorf("redactURL(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
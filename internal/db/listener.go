@@ -0,0 +1,82 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// GoodsChannel канал, в который триггер на goods отправляет изменения
+const GoodsChannel = "goods_changed"
+
+const listenRetryDelay = time.Second
+
+// GoodChange описывает изменённые одним запросом строки goods одного проекта
+type GoodChange struct {
+	ProjectID int64   `json:"project_id"`
+	IDs       []int64 `json:"ids"`
+}
+
+// Listener получает уведомления об изменениях goods на отдельном соединении.
+// Уведомления, отправленные пока соединение было разорвано, теряются.
+type Listener struct {
+	dsn      string
+	onChange func(ctx context.Context, change GoodChange)
+	logger   *slog.Logger
+}
+
+func NewListener(dsn string, onChange func(ctx context.Context, change GoodChange), logger *slog.Logger) *Listener {
+	return &Listener{
+		dsn:      dsn,
+		onChange: onChange,
+		logger:   logger,
+	}
+}
+
+// Run слушает уведомления до отмены ctx, переподключаясь после ошибок
+func (l *Listener) Run(ctx context.Context) {
+	for {
+		err := l.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		l.logger.Warn("listening for goods changes failed, reconnecting", "error", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(listenRetryDelay):
+		}
+	}
+}
+
+func (l *Listener) listen(ctx context.Context) error {
+	conn, err := pgx.Connect(ctx, l.dsn)
+	if err != nil {
+		return fmt.Errorf("connect: %w", err)
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+GoodsChannel); err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("wait for notification: %w", err)
+		}
+
+		var change GoodChange
+		if err := json.Unmarshal([]byte(notification.Payload), &change); err != nil {
+			l.logger.Warn("invalid goods change notification", "payload", notification.Payload, "error", err)
+			continue
+		}
+
+		l.onChange(ctx, change)
+	}
+}
@@ -4,20 +4,21 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 )
 
-func RunMigrations(db *sql.DB, migrationsDir string) error {
+func RunMigrations(db *sql.DB, migrationsDir string, logger *slog.Logger) error {
 	files, err := filepath.Glob(filepath.Join(migrationsDir, "*.sql"))
 	if err != nil {
 		return fmt.Errorf("listing migration files: %w", err)
 	}
 
 	for _, file := range files {
-		fmt.Printf("Running migration: %s\n", file)
+		logger.Info("running migration", "file", file)
 		content, err := os.ReadFile(file)
 		if err != nil {
 			return fmt.Errorf("reading migration file: %w", err)
@@ -0,0 +1,53 @@
+package events
+
+import (
+	"time"
+
+	"github.com/yangirxd/goods-service/internal/models"
+)
+
+// Данные событий версии v1. Поля можно только добавлять: удаление или
+// изменение типа поля требует нового типа события с суффиксом v2.
+
+// GoodV1 данные событий goods.good.created.v1 и goods.good.updated.v1
+type GoodV1 struct {
+	ID          int64     `json:"id"`
+	ProjectID   int64     `json:"project_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Priority    int       `json:"priority"`
+	Removed     bool      `json:"removed"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// GoodDeletedV1 данные события goods.good.deleted.v1
+type GoodDeletedV1 struct {
+	ID        int64 `json:"id"`
+	ProjectID int64 `json:"project_id"`
+}
+
+// GoodReprioritizedV1 данные события goods.good.reprioritized.v1
+type GoodReprioritizedV1 struct {
+	ID          int64        `json:"id"`
+	ProjectID   int64        `json:"project_id"`
+	NewPriority int          `json:"new_priority"`
+	Priorities  []PriorityV1 `json:"priorities"`
+}
+
+// PriorityV1 новый приоритет одного из затронутых товаров
+type PriorityV1 struct {
+	ID       int64 `json:"id"`
+	Priority int   `json:"priority"`
+}
+
+func newGoodV1(good *models.Good) GoodV1 {
+	return GoodV1{
+		ID:          good.ID,
+		ProjectID:   good.ProjectID,
+		Name:        good.Name,
+		Description: good.Description,
+		Priority:    good.Priority,
+		Removed:     good.Removed,
+		CreatedAt:   good.CreatedAt,
+	}
+}
@@ -0,0 +1,128 @@
+package events
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yangirxd/goods-service/internal/models"
+)
+
+const (
+	SpecVersion = "1.0"
+	Source      = "/goods-service"
+)
+
+// Типы событий. Суффикс версии меняется при любом несовместимом изменении данных.
+const (
+	TypeGoodCreated       = "goods.good.created.v1"
+	TypeGoodUpdated       = "goods.good.updated.v1"
+	TypeGoodDeleted       = "goods.good.deleted.v1"
+	TypeGoodReprioritized = "goods.good.reprioritized.v1"
+)
+
+var actions = map[string]string{
+	TypeGoodCreated:       models.ActionCreate,
+	TypeGoodUpdated:       models.ActionUpdate,
+	TypeGoodDeleted:       models.ActionDelete,
+	TypeGoodReprioritized: models.ActionReprioritize,
+}
+
+// Event представляет событие в формате CloudEvents 1.0 (structured JSON mode).
+// Subject содержит ID товара, расширение projectid — ID проекта, расширение
+// requestid — ID HTTP-запроса, который вызвал изменение, расширение actor —
+// аутентифицированный субъект запроса.
+type Event struct {
+	SpecVersion     string      `json:"specversion"`
+	ID              string      `json:"id"`
+	Source          string      `json:"source"`
+	Type            string      `json:"type"`
+	Subject         string      `json:"subject"`
+	Time            time.Time   `json:"time"`
+	DataContentType string      `json:"datacontenttype"`
+	ProjectID       int64       `json:"projectid"`
+	RequestID       string      `json:"requestid,omitempty"`
+	Actor           string      `json:"actor,omitempty"`
+	Data            interface{} `json:"data"`
+
+	// legacyData — данные в формате, который публиковался в goods.logs до
+	// перехода на CloudEvents. В JSON события не попадают.
+	legacyData interface{}
+}
+
+// Action возвращает действие, соответствующее типу события
+func (e *Event) Action() (string, bool) {
+	action, ok := actions[e.Type]
+	return action, ok
+}
+
+// LegacyData возвращает данные события в прежнем формате goods.logs.
+// Для событий, полученных из NATS, возвращает nil.
+func (e *Event) LegacyData() interface{} {
+	return e.legacyData
+}
+
+// GoodID возвращает ID товара из subject события
+func (e *Event) GoodID() (int64, error) {
+	return strconv.ParseInt(e.Subject, 10, 64)
+}
+
+func newEvent(eventType string, projectID, goodID int64, data interface{}) *Event {
+	return &Event{
+		SpecVersion:     SpecVersion,
+		ID:              uuid.NewString(),
+		Source:          Source,
+		Type:            eventType,
+		Subject:         strconv.FormatInt(goodID, 10),
+		Time:            time.Now().UTC(),
+		DataContentType: "application/json",
+		ProjectID:       projectID,
+		Data:            data,
+	}
+}
+
+// GoodCreated создаёт событие о создании товара
+func GoodCreated(good *models.Good) *Event {
+	event := newEvent(TypeGoodCreated, good.ProjectID, good.ID, newGoodV1(good))
+	event.legacyData = *good
+	return event
+}
+
+// GoodUpdated создаёт событие об изменении товара
+func GoodUpdated(good *models.Good) *Event {
+	event := newEvent(TypeGoodUpdated, good.ProjectID, good.ID, newGoodV1(good))
+	event.legacyData = *good
+	return event
+}
+
+// GoodDeleted создаёт событие об удалении товара
+func GoodDeleted(good *models.Good) *Event {
+	return newEvent(TypeGoodDeleted, good.ProjectID, good.ID, GoodDeletedV1{
+		ID:        good.ID,
+		ProjectID: good.ProjectID,
+	})
+}
+
+// GoodReprioritized создаёт событие об изменении приоритета товара
+func GoodReprioritized(id, projectID int64, newPriority int, updated []*models.Good) *Event {
+	priorities := make([]PriorityV1, len(updated))
+	for i, good := range updated {
+		priorities[i] = PriorityV1{
+			ID:       good.ID,
+			Priority: good.Priority,
+		}
+	}
+
+	event := newEvent(TypeGoodReprioritized, projectID, id, GoodReprioritizedV1{
+		ID:          id,
+		ProjectID:   projectID,
+		NewPriority: newPriority,
+		Priorities:  priorities,
+	})
+	event.legacyData = map[string]interface{}{
+		"project_id":   projectID,
+		"new_priority": newPriority,
+		"updated_ids":  updated,
+	}
+	return event
+}
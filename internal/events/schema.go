@@ -0,0 +1,18 @@
+package events
+
+import (
+	"embed"
+	"fmt"
+)
+
+//go:embed schemas/*.json
+var schemaFS embed.FS
+
+// Schema возвращает JSON Schema данных события указанного типа
+func Schema(eventType string) ([]byte, error) {
+	data, err := schemaFS.ReadFile("schemas/" + eventType + ".json")
+	if err != nil {
+		return nil, fmt.Errorf("schema %s: %w", eventType, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,259 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// payloads связывает типы событий со структурами их данных
+var payloads = map[string]interface{}{
+	TypeGoodCreated:       GoodV1{},
+	TypeGoodUpdated:       GoodV1{},
+	TypeGoodDeleted:       GoodDeletedV1{},
+	TypeGoodReprioritized: GoodReprioritizedV1{},
+}
+
+// frozenDir содержит копии схем v1 на момент их публикации. Файлы не
+// меняются вместе со схемами в schemas/, поэтому удаление или смена типа
+// поля ломает тест, даже если опубликованную схему поправили тоже.
+const frozenDir = "testdata/v1"
+
+// schemaNode подмножество JSON Schema, достаточное для проверки совместимости
+type schemaNode struct {
+	Type       string                 `json:"type"`
+	Properties map[string]*schemaNode `json:"properties"`
+	Items      *schemaNode            `json:"items"`
+}
+
+func TestPayloadsMatchFrozenSchemas(t *testing.T) {
+	for eventType, schema := range frozenSchemas(t) {
+		t.Run(eventType, func(t *testing.T) {
+			payload, ok := payloads[eventType]
+			if !ok {
+				t.Fatalf("no payload for frozen event type %s", eventType)
+			}
+			if err := checkCompatible(eventType, schema, reflect.TypeOf(payload)); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestPublishedSchemasExtendFrozen(t *testing.T) {
+	for eventType, frozen := range frozenSchemas(t) {
+		t.Run(eventType, func(t *testing.T) {
+			data, err := Schema(eventType)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var published schemaNode
+			if err := json.Unmarshal(data, &published); err != nil {
+				t.Fatalf("parse schema %s: %v", eventType, err)
+			}
+
+			if err := checkExtends(eventType, frozen, &published); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestPayloadsMatchPublishedSchemas(t *testing.T) {
+	for eventType, payload := range payloads {
+		t.Run(eventType, func(t *testing.T) {
+			data, err := Schema(eventType)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var schema schemaNode
+			if err := json.Unmarshal(data, &schema); err != nil {
+				t.Fatalf("parse schema %s: %v", eventType, err)
+			}
+
+			if err := checkCompatible(eventType, &schema, reflect.TypeOf(payload)); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestCheckCompatibleDetectsBreakingChanges(t *testing.T) {
+	schema := &schemaNode{Type: "object", Properties: map[string]*schemaNode{
+		"id":   {Type: "integer"},
+		"name": {Type: "string"},
+	}}
+
+	tests := []struct {
+		name    string
+		payload interface{}
+		wantErr bool
+	}{
+		{"same fields", struct {
+			ID   int64  `json:"id"`
+			Name string `json:"name"`
+		}{}, false},
+		{"added field", struct {
+			ID    int64  `json:"id"`
+			Name  string `json:"name"`
+			Extra bool   `json:"extra"`
+		}{}, false},
+		{"removed field", struct {
+			ID int64 `json:"id"`
+		}{}, true},
+		{"retyped field", struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		}{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkCompatible("test", schema, reflect.TypeOf(tt.payload))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkCompatible() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// frozenSchemas читает замороженные схемы v1 по типам событий
+func frozenSchemas(t *testing.T) map[string]*schemaNode {
+	t.Helper()
+
+	files, err := filepath.Glob(filepath.Join(frozenDir, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatalf("no frozen schemas in %s", frozenDir)
+	}
+
+	schemas := make(map[string]*schemaNode, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var schema schemaNode
+		if err := json.Unmarshal(data, &schema); err != nil {
+			t.Fatalf("parse %s: %v", file, err)
+		}
+		schemas[strings.TrimSuffix(filepath.Base(file), ".json")] = &schema
+	}
+	return schemas
+}
+
+// checkExtends проверяет, что схема next содержит все поля base с теми же типами
+func checkExtends(path string, base, next *schemaNode) error {
+	if base.Type != next.Type {
+		return fmt.Errorf("%s: type changed from %q to %q", path, base.Type, next.Type)
+	}
+
+	for name, property := range base.Properties {
+		nextProperty, ok := next.Properties[name]
+		if !ok {
+			return fmt.Errorf("%s.%s: field removed from schema", path, name)
+		}
+		if err := checkExtends(path+"."+name, property, nextProperty); err != nil {
+			return err
+		}
+	}
+
+	if base.Items != nil {
+		if next.Items == nil {
+			return fmt.Errorf("%s[]: items removed from schema", path)
+		}
+		return checkExtends(path+"[]", base.Items, next.Items)
+	}
+
+	return nil
+}
+
+// checkCompatible проверяет, что каждое поле схемы присутствует в типе t
+// и кодируется в тот же JSON-тип
+func checkCompatible(path string, schema *schemaNode, t reflect.Type) error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if actual := jsonType(t); actual != schema.Type {
+		return fmt.Errorf("%s: schema type %q, payload type %q", path, schema.Type, actual)
+	}
+
+	switch schema.Type {
+	case "object":
+		if t.Kind() != reflect.Struct {
+			return nil
+		}
+		fields := jsonFields(t)
+		for name, property := range schema.Properties {
+			field, ok := fields[name]
+			if !ok {
+				return fmt.Errorf("%s.%s: field removed from payload", path, name)
+			}
+			if err := checkCompatible(path+"."+name, property, field); err != nil {
+				return err
+			}
+		}
+	case "array":
+		if schema.Items != nil {
+			return checkCompatible(path+"[]", schema.Items, t.Elem())
+		}
+	}
+
+	return nil
+}
+
+// jsonFields возвращает типы полей структуры по их именам в JSON
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
+
+// jsonType возвращает тип JSON Schema, в который кодируется тип Go
+func jsonType(t reflect.Type) string {
+	if t == reflect.TypeOf(time.Time{}) {
+		return "string"
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Struct, reflect.Map:
+		return "object"
+	}
+	return ""
+}
@@ -0,0 +1,270 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yangirxd/goods-service/internal/auth"
+	"github.com/yangirxd/goods-service/internal/clickhouse"
+	"github.com/yangirxd/goods-service/internal/models"
+)
+
+const (
+	dateLayout                = "2006-01-02"
+	defaultAnalyticsDays      = 30
+	defaultReprioritizedLimit = 10
+	maxReprioritizedLimit     = 100
+)
+
+type AnalyticsHandler struct {
+	ch       *clickhouse.Client
+	projects *auth.Projects
+}
+
+func NewAnalyticsHandler(ch *clickhouse.Client, projects *auth.Projects) *AnalyticsHandler {
+	return &AnalyticsHandler{
+		ch:       ch,
+		projects: projects,
+	}
+}
+
+// Daily godoc
+// @Summary      Daily event counts
+// @Description  Get number of creates, updates, deletes and reprioritizations per project per day. Requires the viewer role in the requested project.
+// @Tags         analytics
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Param        projectId query int false "Project ID (default: all projects where the caller has a role)"
+// @Param        from query string false "First day of the period, YYYY-MM-DD (default: 30 days ago)"
+// @Param        to query string false "Last day of the period, YYYY-MM-DD (default: today)"
+// @Success      200 {object} models.DailyStatsResponse
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      429 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Router       /analytics/daily [get]
+func (h *AnalyticsHandler) Daily(c *gin.Context) {
+	filter, err := analyticsFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Code:      1,
+			Message:   "errors.validation.failed",
+			Details:   err.Error(),
+			RequestID: requestID(c),
+		})
+		return
+	}
+	if !h.authorize(c, &filter) {
+		return
+	}
+
+	stats, err := h.ch.DailyStats(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Code:      2,
+			Message:   "errors.internal",
+			Details:   err.Error(),
+			RequestID: requestID(c),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.DailyStatsResponse{
+		Meta:  analyticsMeta(filter),
+		Stats: stats,
+	})
+}
+
+// Reprioritized godoc
+// @Summary      Most reprioritized goods
+// @Description  Get goods whose priority was changed most often during the period. Requires the viewer role in the requested project.
+// @Tags         analytics
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Param        projectId query int false "Project ID (default: all projects where the caller has a role)"
+// @Param        from query string false "First day of the period, YYYY-MM-DD (default: 30 days ago)"
+// @Param        to query string false "Last day of the period, YYYY-MM-DD (default: today)"
+// @Param        limit query int false "Limit number of records (default: 10, max: 100)"
+// @Success      200 {object} models.ReprioritizedResponse
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      429 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Router       /analytics/reprioritized [get]
+func (h *AnalyticsHandler) Reprioritized(c *gin.Context) {
+	filter, err := analyticsFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Code:      1,
+			Message:   "errors.validation.failed",
+			Details:   err.Error(),
+			RequestID: requestID(c),
+		})
+		return
+	}
+	if !h.authorize(c, &filter) {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultReprioritizedLimit)))
+	if err != nil || limit < 0 || limit > maxReprioritizedLimit {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Code:      1,
+			Message:   "errors.validation.failed",
+			Details:   "invalid limit",
+			RequestID: requestID(c),
+		})
+		return
+	}
+	filter.Limit = limit
+
+	goods, err := h.ch.TopReprioritized(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Code:      2,
+			Message:   "errors.internal",
+			Details:   err.Error(),
+			RequestID: requestID(c),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ReprioritizedResponse{
+		Meta:  analyticsMeta(filter),
+		Goods: goods,
+	})
+}
+
+// Churn godoc
+// @Summary      Churn rate
+// @Description  Get ratio of deleted to created goods per project during the period. Requires the viewer role in the requested project.
+// @Tags         analytics
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Param        projectId query int false "Project ID (default: all projects where the caller has a role)"
+// @Param        from query string false "First day of the period, YYYY-MM-DD (default: 30 days ago)"
+// @Param        to query string false "Last day of the period, YYYY-MM-DD (default: today)"
+// @Success      200 {object} models.ChurnResponse
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      429 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Router       /analytics/churn [get]
+func (h *AnalyticsHandler) Churn(c *gin.Context) {
+	filter, err := analyticsFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Code:      1,
+			Message:   "errors.validation.failed",
+			Details:   err.Error(),
+			RequestID: requestID(c),
+		})
+		return
+	}
+	if !h.authorize(c, &filter) {
+		return
+	}
+
+	stats, err := h.ch.Churn(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Code:      2,
+			Message:   "errors.internal",
+			Details:   err.Error(),
+			RequestID: requestID(c),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ChurnResponse{
+		Meta:  analyticsMeta(filter),
+		Stats: stats,
+	})
+}
+
+// analyticsFilter разбирает общие для эндпоинтов аналитики параметры запроса
+func analyticsFilter(c *gin.Context) (clickhouse.AnalyticsFilter, error) {
+	var filter clickhouse.AnalyticsFilter
+
+	if projectID := c.Query("projectId"); projectID != "" {
+		id, err := strconv.ParseInt(projectID, 10, 64)
+		if err != nil {
+			return filter, errors.New("invalid project_id")
+		}
+		filter.ProjectIDs = []int64{id}
+	}
+
+	filter.To = time.Now().UTC().Truncate(24 * time.Hour)
+	if to := c.Query("to"); to != "" {
+		date, err := time.Parse(dateLayout, to)
+		if err != nil {
+			return filter, errors.New("invalid to")
+		}
+		filter.To = date
+	}
+
+	filter.From = filter.To.AddDate(0, 0, -(defaultAnalyticsDays - 1))
+	if from := c.Query("from"); from != "" {
+		date, err := time.Parse(dateLayout, from)
+		if err != nil {
+			return filter, errors.New("invalid from")
+		}
+		filter.From = date
+	}
+
+	if filter.From.After(filter.To) {
+		return filter, errors.New("from must not be after to")
+	}
+
+	return filter, nil
+}
+
+// authorize проверяет роль viewer в запрошенном проекте, а без projectId
+// ограничивает выборку проектами, где у субъекта есть роль. Иначе отвечает
+// ошибкой и возвращает false.
+func (h *AnalyticsHandler) authorize(c *gin.Context, filter *clickhouse.AnalyticsFilter) bool {
+	access, ok := requestAccess(c, h.projects)
+	if !ok {
+		return false
+	}
+	if access.All() {
+		return true
+	}
+
+	if filter.ProjectIDs == nil {
+		filter.ProjectIDs = access.Projects(auth.RoleViewer)
+		return true
+	}
+
+	for _, projectID := range filter.ProjectIDs {
+		if !access.Can(projectID, auth.RoleViewer) {
+			c.JSON(http.StatusForbidden, models.ErrorResponse{
+				Code:      5,
+				Message:   "errors.auth.forbidden",
+				Details:   "project role viewer required",
+				RequestID: requestID(c),
+			})
+			return false
+		}
+	}
+	return true
+}
+
+func analyticsMeta(filter clickhouse.AnalyticsFilter) models.AnalyticsMeta {
+	return models.AnalyticsMeta{
+		From: filter.From.Format(dateLayout),
+		To:   filter.To.Format(dateLayout),
+	}
+}
@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yangirxd/goods-service/internal/auth"
+	"github.com/yangirxd/goods-service/internal/models"
+	"github.com/yangirxd/goods-service/internal/repository"
+)
+
+type APIKeyHandler struct {
+	repo   *repository.APIKeyRepository
+	logger *slog.Logger
+}
+
+func NewAPIKeyHandler(repo *repository.APIKeyRepository, logger *slog.Logger) *APIKeyHandler {
+	return &APIKeyHandler{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// Create godoc
+// @Summary      Issue an API key
+// @Description  Issue a long-lived API key with the given scopes. The key is returned only once.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Param        input body models.APIKeyCreate true "Key name, scopes (goods:read, goods:write, admin) and optional expiry"
+// @Success      201 {object} models.APIKeyIssued
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      429 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Router       /admin/api-keys [post]
+func (h *APIKeyHandler) Create(c *gin.Context) {
+	var input models.APIKeyCreate
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Code:      1,
+			Message:   "errors.validation.failed",
+			Details:   err.Error(),
+			RequestID: requestID(c),
+		})
+		return
+	}
+
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Code:      1,
+			Message:   "errors.validation.failed",
+			Details:   "expires_at must be in the future",
+			RequestID: requestID(c),
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	record, key, err := auth.NewAPIKey(&input, auth.Subject(ctx))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Code:      1,
+			Message:   "errors.validation.failed",
+			Details:   err.Error(),
+			RequestID: requestID(c),
+		})
+		return
+	}
+
+	created, err := h.repo.Create(ctx, record)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Code:      2,
+			Message:   "errors.internal",
+			Details:   err.Error(),
+			RequestID: requestID(c),
+		})
+		return
+	}
+
+	h.logger.InfoContext(ctx, "api key issued",
+		"api_key_id", created.ID, "prefix", created.Prefix, "scopes", created.Scopes, "by", created.CreatedBy)
+
+	c.JSON(http.StatusCreated, models.APIKeyIssued{APIKey: *created, Key: key})
+}
+
+// List godoc
+// @Summary      List API keys
+// @Description  List all API keys, including revoked and expired ones, newest first
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Success      200 {object} models.APIKeyListResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      429 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Router       /admin/api-keys [get]
+func (h *APIKeyHandler) List(c *gin.Context) {
+	keys, err := h.repo.List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Code:      2,
+			Message:   "errors.internal",
+			Details:   err.Error(),
+			RequestID: requestID(c),
+		})
+		return
+	}
+
+	response := models.APIKeyListResponse{Keys: make([]models.APIKey, len(keys))}
+	for i, key := range keys {
+		response.Keys[i] = *key
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// Revoke godoc
+// @Summary      Revoke an API key
+// @Description  Revoke an API key by its ID. Requests with a revoked key are rejected immediately.
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Param        id path int true "API key ID"
+// @Success      200 {object} models.APIKey
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      429 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Router       /admin/api-keys/{id} [delete]
+func (h *APIKeyHandler) Revoke(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Code:      1,
+			Message:   "errors.validation.failed",
+			Details:   "invalid id",
+			RequestID: requestID(c),
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	key, err := h.repo.Revoke(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Code:      2,
+			Message:   "errors.internal",
+			Details:   err.Error(),
+			RequestID: requestID(c),
+		})
+		return
+	}
+
+	if key == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Code:      3,
+			Message:   "errors.common.notFound",
+			Details:   struct{}{},
+			RequestID: requestID(c),
+		})
+		return
+	}
+
+	h.logger.InfoContext(ctx, "api key revoked", "api_key_id", key.ID, "prefix", key.Prefix, "by", auth.Subject(ctx))
+
+	c.JSON(http.StatusOK, key)
+}
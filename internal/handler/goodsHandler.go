@@ -1,27 +1,36 @@
 package handler
 
 import (
+	"context"
+	"log/slog"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/yangirxd/goods-service/internal/auth"
 	"github.com/yangirxd/goods-service/internal/cache"
+	"github.com/yangirxd/goods-service/internal/events"
+	"github.com/yangirxd/goods-service/internal/logging"
 	"github.com/yangirxd/goods-service/internal/models"
 	"github.com/yangirxd/goods-service/internal/queue"
 	"github.com/yangirxd/goods-service/internal/repository"
 )
 
 type GoodsHandler struct {
-	repo  *repository.GoodsRepository
-	cache *cache.GoodsCache
-	log   *queue.Logger
+	repo     *repository.GoodsRepository
+	cache    *cache.GoodsCache
+	log      *queue.Logger
+	projects *auth.Projects
+	logger   *slog.Logger
 }
 
-func NewGoodsHandler(repo *repository.GoodsRepository, cache *cache.GoodsCache, log *queue.Logger) *GoodsHandler {
+func NewGoodsHandler(repo *repository.GoodsRepository, cache *cache.GoodsCache, log *queue.Logger, projects *auth.Projects, logger *slog.Logger) *GoodsHandler {
 	return &GoodsHandler{
-		repo:  repo,
-		cache: cache,
-		log:   log,
+		repo:     repo,
+		cache:    cache,
+		log:      log,
+		projects: projects,
+		logger:   logger,
 	}
 }
 
@@ -31,18 +40,38 @@ func NewGoodsHandler(repo *repository.GoodsRepository, cache *cache.GoodsCache,
 // @Tags         goods
 // @Accept       json
 // @Produce      json
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
 // @Param        input body models.GoodCreate true "Good data"
 // @Success      201 {object} models.Good
 // @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      429 {object} models.ErrorResponse
 // @Failure      500 {object} models.ErrorResponse
 // @Router       /goods/create [post]
 func (h *GoodsHandler) Create(c *gin.Context) {
 	var input models.GoodCreate
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Code:    1,
-			Message: "errors.validation.failed",
-			Details: err.Error(),
+			Code:      1,
+			Message:   "errors.validation.failed",
+			Details:   err.Error(),
+			RequestID: requestID(c),
+		})
+		return
+	}
+
+	access, ok := h.access(c)
+	if !ok {
+		return
+	}
+	if !access.Can(input.ProjectID, auth.RoleEditor) {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Code:      5,
+			Message:   "errors.auth.forbidden",
+			Details:   "project role editor required",
+			RequestID: requestID(c),
 		})
 		return
 	}
@@ -50,15 +79,25 @@ func (h *GoodsHandler) Create(c *gin.Context) {
 	good, err := h.repo.Create(c.Request.Context(), &input)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Code:    2,
-			Message: "errors.internal",
-			Details: err.Error(),
+			Code:      2,
+			Message:   "errors.internal",
+			Details:   err.Error(),
+			RequestID: requestID(c),
 		})
 		return
 	}
 
-	if err := h.log.Log("create", good.ID, good); err != nil {
-		println("Error logging create event:", err.Error())
+	// Запрос к ещё не созданному ID мог оставить в кэше отметку об отсутствии
+	if err := h.cache.Refresh(c.Request.Context(), good); err != nil {
+		h.logger.WarnContext(c.Request.Context(), "invalidate cache", "error", err)
+	}
+
+	if err := h.cache.BumpProject(c.Request.Context(), good.ProjectID); err != nil {
+		h.logger.WarnContext(c.Request.Context(), "invalidate list cache", "error", err)
+	}
+
+	if err := h.log.Log(c.Request.Context(), events.GoodCreated(good)); err != nil {
+		h.logger.ErrorContext(c.Request.Context(), "publish create event", "error", err)
 	}
 
 	c.JSON(http.StatusCreated, good)
@@ -70,57 +109,67 @@ func (h *GoodsHandler) Create(c *gin.Context) {
 // @Tags         goods
 // @Accept       json
 // @Produce      json
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
 // @Param        id path int true "Good ID"
 // @Success      200 {object} models.Good
 // @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
 // @Failure      404 {object} models.ErrorResponse
+// @Failure      429 {object} models.ErrorResponse
 // @Failure      500 {object} models.ErrorResponse
 // @Router       /goods/get/{id} [get]
 func (h *GoodsHandler) Get(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Code:    1,
-			Message: "errors.validation.failed",
-			Details: "invalid id",
+			Code:      1,
+			Message:   "errors.validation.failed",
+			Details:   "invalid id",
+			RequestID: requestID(c),
 		})
 		return
 	}
 
-	cacheKey := cache.GoodKey(id)
-	good, err := h.cache.Get(c.Request.Context(), cacheKey)
+	access, ok := h.access(c)
+	if !ok {
+		return
+	}
+
+	// Товар загружается с правами субъекта запроса, то есть под RLS. Кэш
+	// общий для всех субъектов, поэтому отсутствие товара запоминается
+	// только после загрузки без ограничения проектами, а права на товар из
+	// кэша проверяются ниже.
+	ctx := c.Request.Context()
+	load := func(ctx context.Context) (*models.Good, error) {
+		return h.repo.Get(ctx, id)
+	}
+	var good *models.Good
+	if access.All() {
+		good, err = h.cache.GetOrLoad(ctx, cache.GoodKey(id), load)
+	} else {
+		good, err = h.cache.GetOrLoadAs(ctx, cache.GoodKey(id), auth.Subject(ctx), load)
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Code:    2,
-			Message: "errors.internal",
-			Details: err.Error(),
+			Code:      2,
+			Message:   "errors.internal",
+			Details:   err.Error(),
+			RequestID: requestID(c),
 		})
 		return
 	}
 
-	if good == nil {
-		good, err = h.repo.Get(c.Request.Context(), id)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-				Code:    2,
-				Message: "errors.internal",
-				Details: err.Error(),
-			})
-			return
-		}
-
-		if good == nil {
-			c.JSON(http.StatusNotFound, models.ErrorResponse{
-				Code:    3,
-				Message: "errors.common.notFound",
-				Details: struct{}{},
-			})
-			return
-		}
-
-		if err := h.cache.Set(c.Request.Context(), cacheKey, good); err != nil {
-			println("Error caching good:", err.Error())
-		}
+	// Товары чужих проектов не отличаются от несуществующих
+	if good == nil || !access.Can(good.ProjectID, auth.RoleViewer) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Code:      3,
+			Message:   "errors.common.notFound",
+			Details:   struct{}{},
+			RequestID: requestID(c),
+		})
+		return
 	}
 
 	c.JSON(http.StatusOK, good)
@@ -132,20 +181,26 @@ func (h *GoodsHandler) Get(c *gin.Context) {
 // @Tags         goods
 // @Accept       json
 // @Produce      json
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
 // @Param        id path int true "Good ID"
 // @Param        input body models.GoodUpdate true "Good update data"
 // @Success      200 {object} models.Good
 // @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
 // @Failure      404 {object} models.ErrorResponse
+// @Failure      429 {object} models.ErrorResponse
 // @Failure      500 {object} models.ErrorResponse
 // @Router       /goods/update/{id} [patch]
 func (h *GoodsHandler) Update(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Code:    1,
-			Message: "errors.validation.failed",
-			Details: "invalid id",
+			Code:      1,
+			Message:   "errors.validation.failed",
+			Details:   "invalid id",
+			RequestID: requestID(c),
 		})
 		return
 	}
@@ -153,38 +208,49 @@ func (h *GoodsHandler) Update(c *gin.Context) {
 	var input models.GoodUpdate
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Code:    1,
-			Message: "errors.validation.failed",
-			Details: err.Error(),
+			Code:      1,
+			Message:   "errors.validation.failed",
+			Details:   err.Error(),
+			RequestID: requestID(c),
 		})
 		return
 	}
 
+	if !h.authorizeGood(c, id, auth.RoleEditor) {
+		return
+	}
+
 	good, err := h.repo.Update(c.Request.Context(), id, &input)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Code:    2,
-			Message: "errors.internal",
-			Details: err.Error(),
+			Code:      2,
+			Message:   "errors.internal",
+			Details:   err.Error(),
+			RequestID: requestID(c),
 		})
 		return
 	}
 
 	if good == nil {
 		c.JSON(http.StatusNotFound, models.ErrorResponse{
-			Code:    3,
-			Message: "errors.common.notFound",
-			Details: struct{}{},
+			Code:      3,
+			Message:   "errors.common.notFound",
+			Details:   struct{}{},
+			RequestID: requestID(c),
 		})
 		return
 	}
 
-	if err := h.cache.Delete(c.Request.Context(), cache.GoodKey(id)); err != nil {
-		println("Error invalidating cache:", err.Error())
+	if err := h.cache.Refresh(c.Request.Context(), good); err != nil {
+		h.logger.WarnContext(c.Request.Context(), "invalidate cache", "error", err)
 	}
 
-	if err := h.log.Log("update", id, good); err != nil {
-		println("Error logging update event:", err.Error())
+	if err := h.cache.BumpProject(c.Request.Context(), good.ProjectID); err != nil {
+		h.logger.WarnContext(c.Request.Context(), "invalidate list cache", "error", err)
+	}
+
+	if err := h.log.Log(c.Request.Context(), events.GoodUpdated(good)); err != nil {
+		h.logger.ErrorContext(c.Request.Context(), "publish update event", "error", err)
 	}
 
 	c.JSON(http.StatusOK, good)
@@ -196,57 +262,85 @@ func (h *GoodsHandler) Update(c *gin.Context) {
 // @Tags         goods
 // @Accept       json
 // @Produce      json
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
 // @Param        id path int true "Good ID"
 // @Success      204 "No Content"
 // @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
 // @Failure      404 {object} models.ErrorResponse
+// @Failure      429 {object} models.ErrorResponse
 // @Failure      500 {object} models.ErrorResponse
 // @Router       /goods/delete/{id} [delete]
 func (h *GoodsHandler) Delete(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Code:    1,
-			Message: "errors.validation.failed",
-			Details: "invalid id",
+			Code:      1,
+			Message:   "errors.validation.failed",
+			Details:   "invalid id",
+			RequestID: requestID(c),
 		})
 		return
 	}
 
+	access, ok := h.access(c)
+	if !ok {
+		return
+	}
+
 	good, err := h.repo.Get(c.Request.Context(), id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Code:    2,
-			Message: "errors.internal",
-			Details: err.Error(),
+			Code:      2,
+			Message:   "errors.internal",
+			Details:   err.Error(),
+			RequestID: requestID(c),
 		})
 		return
 	}
 
-	if good == nil {
+	if good == nil || !access.Can(good.ProjectID, auth.RoleViewer) {
 		c.JSON(http.StatusNotFound, models.ErrorResponse{
-			Code:    3,
-			Message: "errors.common.notFound",
-			Details: struct{}{},
+			Code:      3,
+			Message:   "errors.common.notFound",
+			Details:   struct{}{},
+			RequestID: requestID(c),
+		})
+		return
+	}
+
+	if !access.Can(good.ProjectID, auth.RoleEditor) {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Code:      5,
+			Message:   "errors.auth.forbidden",
+			Details:   "project role editor required",
+			RequestID: requestID(c),
 		})
 		return
 	}
 
 	if err := h.repo.Delete(c.Request.Context(), id); err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Code:    2,
-			Message: "errors.internal",
-			Details: err.Error(),
+			Code:      2,
+			Message:   "errors.internal",
+			Details:   err.Error(),
+			RequestID: requestID(c),
 		})
 		return
 	}
 
 	if err := h.cache.Delete(c.Request.Context(), cache.GoodKey(id)); err != nil {
-		println("Error invalidating cache:", err.Error())
+		h.logger.WarnContext(c.Request.Context(), "invalidate cache", "error", err)
 	}
 
-	if err := h.log.Log("delete", id, nil); err != nil {
-		println("Error logging delete event:", err.Error())
+	if err := h.cache.BumpProject(c.Request.Context(), good.ProjectID); err != nil {
+		h.logger.WarnContext(c.Request.Context(), "invalidate list cache", "error", err)
+	}
+
+	if err := h.log.Log(c.Request.Context(), events.GoodDeleted(good)); err != nil {
+		h.logger.ErrorContext(c.Request.Context(), "publish delete event", "error", err)
 	}
 
 	c.Status(http.StatusNoContent)
@@ -254,23 +348,30 @@ func (h *GoodsHandler) Delete(c *gin.Context) {
 
 // List godoc
 // @Summary      List goods
-// @Description  Get list of goods with pagination
+// @Description  Get list of goods with pagination and Redis caching. Without projectId only projects where the caller has a role are listed.
 // @Tags         goods
 // @Accept       json
 // @Produce      json
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Param        projectId query int false "Project ID (default: all projects)"
 // @Param        limit query int false "Limit number of records (default: 10)"
 // @Param        offset query int false "Offset for pagination (default: 0)"
 // @Success      200 {object} models.ListResponse
 // @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      429 {object} models.ErrorResponse
 // @Failure      500 {object} models.ErrorResponse
 // @Router       /goods/list [get]
 func (h *GoodsHandler) List(c *gin.Context) {
 	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
 	if err != nil || limit < 0 {
 		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Code:    1,
-			Message: "errors.validation.failed",
-			Details: "invalid limit",
+			Code:      1,
+			Message:   "errors.validation.failed",
+			Details:   "invalid limit",
+			RequestID: requestID(c),
 		})
 		return
 	}
@@ -278,36 +379,90 @@ func (h *GoodsHandler) List(c *gin.Context) {
 	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
 	if err != nil || offset < 0 {
 		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Code:    1,
-			Message: "errors.validation.failed",
-			Details: "invalid offset",
+			Code:      1,
+			Message:   "errors.validation.failed",
+			Details:   "invalid offset",
+			RequestID: requestID(c),
 		})
 		return
 	}
 
-	goods, total, removed, err := h.repo.List(c.Request.Context(), limit, offset)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Code:    2,
-			Message: "errors.internal",
-			Details: err.Error(),
-		})
+	var projectID int64
+	if value := c.Query("projectId"); value != "" {
+		projectID, err = strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Code:      1,
+				Message:   "errors.validation.failed",
+				Details:   "invalid project_id",
+				RequestID: requestID(c),
+			})
+			return
+		}
+	}
+
+	access, ok := h.access(c)
+	if !ok {
 		return
 	}
 
-	goodsResponse := make([]models.Good, len(goods))
-	for i, g := range goods {
-		goodsResponse[i] = *g
+	// Без projectId список включает только доступные субъекту проекты
+	var projectIDs []int64
+	switch {
+	case projectID != 0:
+		if !access.Can(projectID, auth.RoleViewer) {
+			c.JSON(http.StatusForbidden, models.ErrorResponse{
+				Code:      5,
+				Message:   "errors.auth.forbidden",
+				Details:   "project role viewer required",
+				RequestID: requestID(c),
+			})
+			return
+		}
+		projectIDs = []int64{projectID}
+	case !access.All():
+		projectIDs = access.Projects(auth.RoleViewer)
 	}
 
-	response := models.ListResponse{
-		Meta: models.ListMeta{
-			Total:   total,
-			Removed: removed,
-			Limit:   limit,
-			Offset:  offset,
-		},
-		Goods: goodsResponse,
+	load := func(ctx context.Context) (*models.ListResponse, error) {
+		goods, total, removed, err := h.repo.List(ctx, projectIDs, limit, offset)
+		if err != nil {
+			return nil, err
+		}
+
+		goodsResponse := make([]models.Good, len(goods))
+		for i, g := range goods {
+			goodsResponse[i] = *g
+		}
+
+		return &models.ListResponse{
+			Meta: models.ListMeta{
+				Total:   total,
+				Removed: removed,
+				Limit:   limit,
+				Offset:  offset,
+			},
+			Goods: goodsResponse,
+		}, nil
+	}
+
+	// Кэш хранит страницы одного проекта или всех сразу, поэтому
+	// страницы из нескольких доступных проектов загружаются из базы
+	var response *models.ListResponse
+	if projectID == 0 && projectIDs != nil {
+		response, err = load(c.Request.Context())
+	} else {
+		query := cache.ListQuery{ProjectID: projectID, Limit: limit, Offset: offset}
+		response, err = h.cache.GetOrLoadList(c.Request.Context(), query, load)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Code:      2,
+			Message:   "errors.internal",
+			Details:   err.Error(),
+			RequestID: requestID(c),
+		})
+		return
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -319,21 +474,27 @@ func (h *GoodsHandler) List(c *gin.Context) {
 // @Tags         goods
 // @Accept       json
 // @Produce      json
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
 // @Param        id query int true "Good ID"
 // @Param        projectId query int true "Project ID"
 // @Param        input body models.ReprioritizeRequest true "New priority"
 // @Success      200 {object} models.ReprioritizeResponse
 // @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
 // @Failure      404 {object} models.ErrorResponse
+// @Failure      429 {object} models.ErrorResponse
 // @Failure      500 {object} models.ErrorResponse
 // @Router       /goods/reprioritize [patch]
 func (h *GoodsHandler) Reprioritize(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Query("id"), 10, 64)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Code:    1,
-			Message: "errors.validation.failed",
-			Details: "invalid id",
+			Code:      1,
+			Message:   "errors.validation.failed",
+			Details:   "invalid id",
+			RequestID: requestID(c),
 		})
 		return
 	}
@@ -341,9 +502,10 @@ func (h *GoodsHandler) Reprioritize(c *gin.Context) {
 	projectID, err := strconv.ParseInt(c.Query("projectId"), 10, 64)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Code:    1,
-			Message: "errors.validation.failed",
-			Details: "invalid project_id",
+			Code:      1,
+			Message:   "errors.validation.failed",
+			Details:   "invalid project_id",
+			RequestID: requestID(c),
 		})
 		return
 	}
@@ -351,9 +513,24 @@ func (h *GoodsHandler) Reprioritize(c *gin.Context) {
 	var input models.ReprioritizeRequest
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Code:    1,
-			Message: "errors.validation.failed",
-			Details: err.Error(),
+			Code:      1,
+			Message:   "errors.validation.failed",
+			Details:   err.Error(),
+			RequestID: requestID(c),
+		})
+		return
+	}
+
+	access, ok := h.access(c)
+	if !ok {
+		return
+	}
+	if !access.Can(projectID, auth.RoleEditor) {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Code:      5,
+			Message:   "errors.auth.forbidden",
+			Details:   "project role editor required",
+			RequestID: requestID(c),
 		})
 		return
 	}
@@ -361,34 +538,34 @@ func (h *GoodsHandler) Reprioritize(c *gin.Context) {
 	updatedGoods, err := h.repo.Reprioritize(c.Request.Context(), id, projectID, input.NewPriority)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Code:    2,
-			Message: "errors.internal",
-			Details: err.Error(),
+			Code:      2,
+			Message:   "errors.internal",
+			Details:   err.Error(),
+			RequestID: requestID(c),
 		})
 		return
 	}
 
 	if updatedGoods == nil {
 		c.JSON(http.StatusNotFound, models.ErrorResponse{
-			Code:    3,
-			Message: "errors.common.notFound",
-			Details: struct{}{},
+			Code:      3,
+			Message:   "errors.common.notFound",
+			Details:   struct{}{},
+			RequestID: requestID(c),
 		})
 		return
 	}
 
-	for _, good := range updatedGoods {
-		if err := h.cache.Delete(c.Request.Context(), cache.GoodKey(good.ID)); err != nil {
-			println("Error invalidating cache:", err.Error())
-		}
+	if err := h.cache.Refresh(c.Request.Context(), updatedGoods...); err != nil {
+		h.logger.WarnContext(c.Request.Context(), "invalidate cache", "error", err)
+	}
+
+	if err := h.cache.BumpProject(c.Request.Context(), projectID); err != nil {
+		h.logger.WarnContext(c.Request.Context(), "invalidate list cache", "error", err)
 	}
 
-	if err := h.log.Log("reprioritize", id, map[string]interface{}{
-		"project_id":   projectID,
-		"new_priority": input.NewPriority,
-		"updated_ids":  updatedGoods,
-	}); err != nil {
-		println("Error logging reprioritize event:", err.Error())
+	if err := h.log.Log(c.Request.Context(), events.GoodReprioritized(id, projectID, input.NewPriority, updatedGoods)); err != nil {
+		h.logger.ErrorContext(c.Request.Context(), "publish reprioritize event", "error", err)
 	}
 
 	priorities := make([]models.PriorityInfo, len(updatedGoods))
@@ -403,3 +580,75 @@ func (h *GoodsHandler) Reprioritize(c *gin.Context) {
 		Priorities: priorities,
 	})
 }
+
+func (h *GoodsHandler) access(c *gin.Context) (*auth.Access, bool) {
+	return requestAccess(c, h.projects)
+}
+
+// requestAccess возвращает права субъекта запроса на проекты и кладёт их в
+// контекст запроса: по ним репозиторий ограничивает строки, видимые
+// транзакции. Если права не удалось получить, отвечает 500 и возвращает false.
+func requestAccess(c *gin.Context, projects *auth.Projects) (*auth.Access, bool) {
+	access, err := projects.Access(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Code:      2,
+			Message:   "errors.internal",
+			Details:   err.Error(),
+			RequestID: requestID(c),
+		})
+		return nil, false
+	}
+	c.Request = c.Request.WithContext(auth.WithAccess(c.Request.Context(), access))
+	return access, true
+}
+
+// authorizeGood проверяет, что у субъекта есть роль в проекте товара.
+// Товар чужого проекта считается несуществующим: на него отвечается 404,
+// а 403 — только если товар виден, но роли для операции не хватает.
+func (h *GoodsHandler) authorizeGood(c *gin.Context, id int64, role string) bool {
+	access, ok := h.access(c)
+	if !ok {
+		return false
+	}
+	if access.All() {
+		return true
+	}
+
+	good, err := h.repo.Get(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Code:      2,
+			Message:   "errors.internal",
+			Details:   err.Error(),
+			RequestID: requestID(c),
+		})
+		return false
+	}
+
+	if good == nil || !access.Can(good.ProjectID, auth.RoleViewer) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Code:      3,
+			Message:   "errors.common.notFound",
+			Details:   struct{}{},
+			RequestID: requestID(c),
+		})
+		return false
+	}
+
+	if !access.Can(good.ProjectID, role) {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Code:      5,
+			Message:   "errors.auth.forbidden",
+			Details:   "project role " + role + " required",
+			RequestID: requestID(c),
+		})
+		return false
+	}
+	return true
+}
+
+// requestID возвращает ID запроса для ответа с ошибкой
+func requestID(c *gin.Context) string {
+	return logging.RequestID(c.Request.Context())
+}
@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yangirxd/goods-service/internal/health"
+	"github.com/yangirxd/goods-service/internal/models"
+)
+
+type HealthHandler struct {
+	checker *health.Checker
+}
+
+func NewHealthHandler(checker *health.Checker) *HealthHandler {
+	return &HealthHandler{checker: checker}
+}
+
+// Liveness godoc
+// @Summary      Liveness probe
+// @Description  Report that the process is alive. Dependencies are not checked.
+// @Tags         health
+// @Produce      json
+// @Success      200 {object} models.HealthResponse
+// @Router       /healthz [get]
+func (h *HealthHandler) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, models.HealthResponse{Status: health.StatusUp})
+}
+
+// Readiness godoc
+// @Summary      Readiness probe
+// @Description  Check Postgres, Redis, NATS and ClickHouse. Returns 503 if any critical dependency is down.
+// @Tags         health
+// @Produce      json
+// @Success      200 {object} models.ReadinessResponse
+// @Failure      503 {object} models.ReadinessResponse
+// @Router       /readyz [get]
+func (h *HealthHandler) Readiness(c *gin.Context) {
+	result := h.checker.Check(c.Request.Context())
+
+	status := http.StatusOK
+	if result.Status != health.StatusUp {
+		status = http.StatusServiceUnavailable
+	}
+
+	c.JSON(status, result)
+}
@@ -0,0 +1,241 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yangirxd/goods-service/internal/auth"
+	"github.com/yangirxd/goods-service/internal/clickhouse"
+	"github.com/yangirxd/goods-service/internal/models"
+	"github.com/yangirxd/goods-service/internal/repository"
+)
+
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 1000
+)
+
+type HistoryHandler struct {
+	ch       *clickhouse.Client
+	repo     *repository.GoodsRepository
+	projects *auth.Projects
+}
+
+func NewHistoryHandler(ch *clickhouse.Client, repo *repository.GoodsRepository, projects *auth.Projects) *HistoryHandler {
+	return &HistoryHandler{
+		ch:       ch,
+		repo:     repo,
+		projects: projects,
+	}
+}
+
+// GoodHistory godoc
+// @Summary      Get history of a good
+// @Description  Get events recorded for a good, newest first, including reprioritize events of other goods that shifted its priority. Requires the viewer role in the good's project.
+// @Tags         history
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Param        id path int true "Good ID"
+// @Param        from query string false "Start of the time range (RFC3339, inclusive)"
+// @Param        to query string false "End of the time range (RFC3339, exclusive)"
+// @Param        action query []string false "Actions to include" collectionFormat(multi) Enums(create, update, delete, reprioritize)
+// @Param        limit query int false "Limit number of records (default: 50, max: 1000)"
+// @Param        offset query int false "Offset for pagination (default: 0)"
+// @Success      200 {object} models.HistoryResponse
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      429 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Router       /goods/{id}/history [get]
+func (h *HistoryHandler) GoodHistory(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Code:      1,
+			Message:   "errors.validation.failed",
+			Details:   "invalid id",
+			RequestID: requestID(c),
+		})
+		return
+	}
+
+	filter, err := historyFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Code:      1,
+			Message:   "errors.validation.failed",
+			Details:   err.Error(),
+			RequestID: requestID(c),
+		})
+		return
+	}
+	filter.EntityID = id
+
+	access, ok := requestAccess(c, h.projects)
+	if !ok {
+		return
+	}
+
+	// История товара чужого проекта, как и сам товар, считается
+	// несуществующей. Удалённые товары остаются в таблице, поэтому их
+	// история доступна участникам проекта.
+	if !access.All() {
+		projectID, found, err := h.repo.ProjectOf(c.Request.Context(), id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Code:      2,
+				Message:   "errors.internal",
+				Details:   err.Error(),
+				RequestID: requestID(c),
+			})
+			return
+		}
+		if !found || !access.Can(projectID, auth.RoleViewer) {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Code:      3,
+				Message:   "errors.common.notFound",
+				Details:   struct{}{},
+				RequestID: requestID(c),
+			})
+			return
+		}
+		filter.ProjectID = projectID
+	}
+
+	h.respond(c, filter)
+}
+
+// ProjectHistory godoc
+// @Summary      Get history of a project
+// @Description  Get events recorded for all goods of a project, newest first. Requires the viewer role in the project.
+// @Tags         history
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Param        id path int true "Project ID"
+// @Param        from query string false "Start of the time range (RFC3339, inclusive)"
+// @Param        to query string false "End of the time range (RFC3339, exclusive)"
+// @Param        action query []string false "Actions to include" collectionFormat(multi) Enums(create, update, delete, reprioritize)
+// @Param        limit query int false "Limit number of records (default: 50, max: 1000)"
+// @Param        offset query int false "Offset for pagination (default: 0)"
+// @Success      200 {object} models.HistoryResponse
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      429 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Router       /projects/{id}/history [get]
+func (h *HistoryHandler) ProjectHistory(c *gin.Context) {
+	projectID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Code:      1,
+			Message:   "errors.validation.failed",
+			Details:   "invalid project_id",
+			RequestID: requestID(c),
+		})
+		return
+	}
+
+	filter, err := historyFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Code:      1,
+			Message:   "errors.validation.failed",
+			Details:   err.Error(),
+			RequestID: requestID(c),
+		})
+		return
+	}
+	filter.ProjectID = projectID
+
+	access, ok := requestAccess(c, h.projects)
+	if !ok {
+		return
+	}
+	if !access.Can(projectID, auth.RoleViewer) {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Code:      5,
+			Message:   "errors.auth.forbidden",
+			Details:   "project role viewer required",
+			RequestID: requestID(c),
+		})
+		return
+	}
+
+	h.respond(c, filter)
+}
+
+func (h *HistoryHandler) respond(c *gin.Context, filter clickhouse.HistoryFilter) {
+	events, err := h.ch.History(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Code:      2,
+			Message:   "errors.internal",
+			Details:   err.Error(),
+			RequestID: requestID(c),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.HistoryResponse{
+		Meta: models.HistoryMeta{
+			Limit:  filter.Limit,
+			Offset: filter.Offset,
+		},
+		Events: events,
+	})
+}
+
+// historyFilter разбирает общие для эндпоинтов истории параметры запроса
+func historyFilter(c *gin.Context) (clickhouse.HistoryFilter, error) {
+	var filter clickhouse.HistoryFilter
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultHistoryLimit)))
+	if err != nil || limit < 0 || limit > maxHistoryLimit {
+		return filter, errors.New("invalid limit")
+	}
+	filter.Limit = limit
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		return filter, errors.New("invalid offset")
+	}
+	filter.Offset = offset
+
+	if from := c.Query("from"); from != "" {
+		filter.From, err = time.Parse(time.RFC3339, from)
+		if err != nil {
+			return filter, errors.New("invalid from")
+		}
+	}
+
+	if to := c.Query("to"); to != "" {
+		filter.To, err = time.Parse(time.RFC3339, to)
+		if err != nil {
+			return filter, errors.New("invalid to")
+		}
+	}
+
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return filter, errors.New("from must be before to")
+	}
+
+	for _, action := range c.QueryArray("action") {
+		if !models.IsValidAction(action) {
+			return filter, fmt.Errorf("invalid action: %s", action)
+		}
+		filter.Actions = append(filter.Actions, action)
+	}
+
+	return filter, nil
+}
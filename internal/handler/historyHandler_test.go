@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yangirxd/goods-service/internal/clickhouse"
+)
+
+func TestHistoryFilter(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+
+	tests := []struct {
+		name    string
+		query   string
+		want    clickhouse.HistoryFilter
+		wantErr bool
+	}{
+		{"defaults", "", clickhouse.HistoryFilter{Limit: defaultHistoryLimit}, false},
+		{
+			name:  "all parameters",
+			query: "limit=5&offset=10&from=2024-01-01T00:00:00Z&to=2024-01-02T00:00:00Z&action=create&action=delete",
+			want: clickhouse.HistoryFilter{
+				Limit:   5,
+				Offset:  10,
+				From:    from,
+				To:      to,
+				Actions: []string{"create", "delete"},
+			},
+		},
+		{"limit too large", "limit=100000", clickhouse.HistoryFilter{}, true},
+		{"negative offset", "offset=-1", clickhouse.HistoryFilter{}, true},
+		{"bad from", "from=yesterday", clickhouse.HistoryFilter{}, true},
+		{"from after to", "from=2024-01-02T00:00:00Z&to=2024-01-01T00:00:00Z", clickhouse.HistoryFilter{}, true},
+		{"unknown action", "action=archive", clickhouse.HistoryFilter{}, true},
+	}
+
+	gin.SetMode(gin.TestMode)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, "/goods/1/history?"+tt.query, nil)
+
+			got, err := historyFilter(c)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("historyFilter() = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("historyFilter() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("historyFilter() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yangirxd/goods-service/internal/auth"
+	"github.com/yangirxd/goods-service/internal/models"
+	"github.com/yangirxd/goods-service/internal/repository"
+)
+
+type ProjectMemberHandler struct {
+	repo     *repository.ProjectMemberRepository
+	projects *auth.Projects
+	logger   *slog.Logger
+}
+
+func NewProjectMemberHandler(repo *repository.ProjectMemberRepository, projects *auth.Projects, logger *slog.Logger) *ProjectMemberHandler {
+	return &ProjectMemberHandler{
+		repo:     repo,
+		projects: projects,
+		logger:   logger,
+	}
+}
+
+// List godoc
+// @Summary      List project members
+// @Description  List subjects with a role in the project. Requires the project admin role.
+// @Tags         projects
+// @Produce      json
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Param        id path int true "Project ID"
+// @Success      200 {object} models.ProjectMemberListResponse
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      429 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Router       /projects/{id}/members [get]
+func (h *ProjectMemberHandler) List(c *gin.Context) {
+	projectID, ok := h.authorize(c)
+	if !ok {
+		return
+	}
+
+	members, err := h.repo.List(c.Request.Context(), projectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Code:      2,
+			Message:   "errors.internal",
+			Details:   err.Error(),
+			RequestID: requestID(c),
+		})
+		return
+	}
+
+	response := models.ProjectMemberListResponse{Members: make([]models.ProjectMember, len(members))}
+	for i, member := range members {
+		response.Members[i] = *member
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// Put godoc
+// @Summary      Grant a project role
+// @Description  Grant the subject a role in the project or change the existing one. Subjects are JWT sub claims or apikey:<prefix> for API keys. Requires the project admin role.
+// @Tags         projects
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Param        id path int true "Project ID"
+// @Param        subject path string true "Subject"
+// @Param        input body models.ProjectMemberUpdate true "Role: viewer, editor or admin"
+// @Success      200 {object} models.ProjectMember
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      429 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Router       /projects/{id}/members/{subject} [put]
+func (h *ProjectMemberHandler) Put(c *gin.Context) {
+	projectID, ok := h.authorize(c)
+	if !ok {
+		return
+	}
+
+	var input models.ProjectMemberUpdate
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Code:      1,
+			Message:   "errors.validation.failed",
+			Details:   err.Error(),
+			RequestID: requestID(c),
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	member, err := h.repo.Put(ctx, &models.ProjectMember{
+		ProjectID: projectID,
+		Subject:   c.Param("subject"),
+		Role:      input.Role,
+		CreatedBy: auth.Subject(ctx),
+	})
+	if errors.Is(err, repository.ErrProjectNotFound) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Code:      3,
+			Message:   "errors.common.notFound",
+			Details:   struct{}{},
+			RequestID: requestID(c),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Code:      2,
+			Message:   "errors.internal",
+			Details:   err.Error(),
+			RequestID: requestID(c),
+		})
+		return
+	}
+
+	h.logger.InfoContext(ctx, "project role granted",
+		"project_id", member.ProjectID, "subject", member.Subject, "role", member.Role, "by", auth.Subject(ctx))
+
+	c.JSON(http.StatusOK, member)
+}
+
+// Delete godoc
+// @Summary      Revoke a project role
+// @Description  Remove the subject from the project. Requires the project admin role.
+// @Tags         projects
+// @Produce      json
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Param        id path int true "Project ID"
+// @Param        subject path string true "Subject"
+// @Success      204 "No Content"
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      429 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Router       /projects/{id}/members/{subject} [delete]
+func (h *ProjectMemberHandler) Delete(c *gin.Context) {
+	projectID, ok := h.authorize(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	subject := c.Param("subject")
+	deleted, err := h.repo.Delete(ctx, projectID, subject)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Code:      2,
+			Message:   "errors.internal",
+			Details:   err.Error(),
+			RequestID: requestID(c),
+		})
+		return
+	}
+
+	if !deleted {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Code:      3,
+			Message:   "errors.common.notFound",
+			Details:   struct{}{},
+			RequestID: requestID(c),
+		})
+		return
+	}
+
+	h.logger.InfoContext(ctx, "project role revoked", "project_id", projectID, "subject", subject, "by", auth.Subject(ctx))
+
+	c.Status(http.StatusNoContent)
+}
+
+// authorize разбирает ID проекта из пути и проверяет, что субъект запроса
+// администрирует проект. Иначе отвечает ошибкой и возвращает false.
+func (h *ProjectMemberHandler) authorize(c *gin.Context) (int64, bool) {
+	projectID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Code:      1,
+			Message:   "errors.validation.failed",
+			Details:   "invalid project_id",
+			RequestID: requestID(c),
+		})
+		return 0, false
+	}
+
+	access, err := h.projects.Access(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Code:      2,
+			Message:   "errors.internal",
+			Details:   err.Error(),
+			RequestID: requestID(c),
+		})
+		return 0, false
+	}
+
+	if !access.Can(projectID, auth.RoleAdmin) {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Code:      5,
+			Message:   "errors.auth.forbidden",
+			Details:   "project role admin required",
+			RequestID: requestID(c),
+		})
+		return 0, false
+	}
+	return projectID, true
+}
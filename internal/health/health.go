@@ -0,0 +1,131 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/yangirxd/goods-service/internal/models"
+)
+
+// Статусы зависимостей и сервиса в целом
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// CheckFunc проверяет доступность зависимости
+type CheckFunc func(ctx context.Context) error
+
+// DegradedFunc сообщает, что сервис обходит зависимость, например кэш
+// после ошибок Redis читает товары напрямую из PostgreSQL
+type DegradedFunc func() bool
+
+type dependency struct {
+	name     string
+	check    CheckFunc
+	degraded DegradedFunc
+}
+
+// Checker проверяет зависимости сервиса. Недоступность критичной зависимости
+// делает сервис неготовым, недоступность необязательной только отражается в ответе.
+type Checker struct {
+	deps     []dependency
+	critical map[string]bool
+	timeout  time.Duration
+}
+
+// NewChecker создаёт проверку готовности. timeout ограничивает время каждой проверки.
+func NewChecker(critical []string, timeout time.Duration) *Checker {
+	c := &Checker{
+		critical: make(map[string]bool, len(critical)),
+		timeout:  timeout,
+	}
+	for _, name := range critical {
+		c.critical[name] = true
+	}
+	return c
+}
+
+// Register добавляет зависимость
+func (c *Checker) Register(name string, check CheckFunc) {
+	c.deps = append(c.deps, dependency{name: name, check: check})
+}
+
+// SetDegraded задаёт проверку деградированного режима зарегистрированной
+// зависимости. Деградированный режим отражается в ответе и не делает
+// сервис неготовым.
+func (c *Checker) SetDegraded(name string, degraded DegradedFunc) {
+	for i := range c.deps {
+		if c.deps[i].name == name {
+			c.deps[i].degraded = degraded
+		}
+	}
+}
+
+// Validate проверяет, что все критичные зависимости зарегистрированы
+func (c *Checker) Validate() error {
+	registered := make(map[string]bool, len(c.deps))
+	for _, dep := range c.deps {
+		registered[dep.name] = true
+	}
+
+	for name := range c.critical {
+		if !registered[name] {
+			return fmt.Errorf("unknown critical dependency %q", name)
+		}
+	}
+	return nil
+}
+
+// Check параллельно проверяет все зависимости
+func (c *Checker) Check(ctx context.Context) models.ReadinessResponse {
+	statuses := make([]models.DependencyStatus, len(c.deps))
+
+	var wg sync.WaitGroup
+	for i, dep := range c.deps {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses[i] = c.checkOne(ctx, dep)
+		}()
+	}
+	wg.Wait()
+
+	status := StatusUp
+	for _, s := range statuses {
+		if s.Critical && s.Status != StatusUp {
+			status = StatusDown
+		}
+	}
+
+	return models.ReadinessResponse{
+		Status:       status,
+		Dependencies: statuses,
+	}
+}
+
+func (c *Checker) checkOne(ctx context.Context, dep dependency) models.DependencyStatus {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := dep.check(ctx)
+
+	status := models.DependencyStatus{
+		Name:      dep.name,
+		Status:    StatusUp,
+		Critical:  c.critical[dep.name],
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		status.Status = StatusDown
+		status.Error = err.Error()
+	}
+	// Режим читается после проверки, которая может его изменить
+	if dep.degraded != nil {
+		status.Degraded = dep.degraded()
+	}
+	return status
+}
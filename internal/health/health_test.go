@@ -0,0 +1,76 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func up(context.Context) error   { return nil }
+func down(context.Context) error { return errors.New("connection refused") }
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		critical []string
+		redis    CheckFunc
+		degraded bool
+		want     string
+	}{
+		{"all up", []string{"postgres"}, up, false, StatusUp},
+		{"optional down", []string{"postgres"}, down, false, StatusUp},
+		{"optional degraded", []string{"postgres"}, up, true, StatusUp},
+		{"critical down", []string{"postgres", "redis"}, down, true, StatusDown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewChecker(tt.critical, time.Second)
+			c.Register("postgres", up)
+			c.Register("redis", tt.redis)
+			c.SetDegraded("redis", func() bool { return tt.degraded })
+
+			result := c.Check(context.Background())
+			if result.Status != tt.want {
+				t.Errorf("Status = %q, want %q", result.Status, tt.want)
+			}
+			if len(result.Dependencies) != 2 {
+				t.Fatalf("got %d dependencies, want 2", len(result.Dependencies))
+			}
+
+			postgres, redis := result.Dependencies[0], result.Dependencies[1]
+			if postgres.Name != "postgres" || postgres.Status != StatusUp || !postgres.Critical || postgres.Degraded {
+				t.Errorf("postgres = %+v", postgres)
+			}
+			if redis.Name != "redis" || redis.Degraded != tt.degraded {
+				t.Errorf("redis = %+v, want degraded %v", redis, tt.degraded)
+			}
+			if (redis.Status == StatusDown) != (redis.Error != "") {
+				t.Errorf("redis = %+v, want an error only when down", redis)
+			}
+		})
+	}
+}
+
+func TestCheckTimeout(t *testing.T) {
+	c := NewChecker(nil, 10*time.Millisecond)
+	c.Register("nats", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	result := c.Check(context.Background())
+	if got := result.Dependencies[0]; got.Status != StatusDown || got.Error != context.DeadlineExceeded.Error() {
+		t.Errorf("nats = %+v, want down with a deadline error", got)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	c := NewChecker([]string{"postgres", "kafka"}, time.Second)
+	c.Register("postgres", up)
+
+	if err := c.Validate(); err == nil {
+		t.Error("Validate() accepted an unregistered critical dependency")
+	}
+}
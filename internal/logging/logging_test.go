@@ -0,0 +1,152 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yangirxd/goods-service/internal/models"
+)
+
+// logLines разбирает журнал в формате JSON по строкам
+func logLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+
+	var lines []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("log line %q: %v", line, err)
+		}
+		lines = append(lines, entry)
+	}
+	return lines
+}
+
+func newTestRouter(t *testing.T, buf *bytes.Buffer, h gin.HandlerFunc) *gin.Engine {
+	t.Helper()
+
+	logger, err := New(buf, FormatJSON, "info")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Middleware(logger), Recovery(logger))
+	r.GET("/goods/get/:id", h)
+	return r
+}
+
+func TestMiddlewareRequestID(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		keep   bool
+	}{
+		{"generated", "", false},
+		{"from client", "req-42", true},
+		{"too long", strings.Repeat("a", maxRequestIDLength+1), false},
+		{"with spaces", "req 42", false},
+		{"not ascii", "запрос", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			var seen string
+			r := newTestRouter(t, &buf, func(c *gin.Context) {
+				seen = RequestID(c.Request.Context())
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/goods/get/1", nil)
+			if tt.header != "" {
+				req.Header.Set(HeaderRequestID, tt.header)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			id := w.Header().Get(HeaderRequestID)
+			if id == "" || id != seen {
+				t.Fatalf("response ID %q, handler saw %q", id, seen)
+			}
+			if (id == tt.header) != tt.keep {
+				t.Errorf("request ID = %q, client sent %q", id, tt.header)
+			}
+
+			lines := logLines(t, &buf)
+			if len(lines) != 1 || lines[0]["request_id"] != id || lines[0]["route"] != "/goods/get/:id" {
+				t.Errorf("log = %v, want one line with request_id %q", lines, id)
+			}
+		})
+	}
+}
+
+func TestContextHandlerAddsRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, FormatJSON, "debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.InfoContext(WithRequestID(context.Background(), "req-1"), "with id")
+	logger.Info("without id")
+
+	lines := logLines(t, &buf)
+	if len(lines) != 2 {
+		t.Fatalf("got %d log lines, want 2", len(lines))
+	}
+	if lines[0]["request_id"] != "req-1" {
+		t.Errorf("first line = %v, want request_id req-1", lines[0])
+	}
+	if _, ok := lines[1]["request_id"]; ok {
+		t.Errorf("second line = %v, want no request_id", lines[1])
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	var buf bytes.Buffer
+	r := newTestRouter(t, &buf, func(c *gin.Context) {
+		panic("nil map")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/goods/get/1", nil)
+	req.Header.Set(HeaderRequestID, "req-42")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", w.Code)
+	}
+	var resp models.ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Code != 2 || resp.RequestID != "req-42" {
+		t.Errorf("response = %+v, want code 2 with request ID", resp)
+	}
+
+	lines := logLines(t, &buf)
+	if len(lines) != 2 {
+		t.Fatalf("got %d log lines, want the panic and the request", len(lines))
+	}
+	panicLine, requestLine := lines[0], lines[1]
+	if panicLine["msg"] != "panic recovered" || panicLine["error"] != "nil map" || panicLine["request_id"] != "req-42" {
+		t.Errorf("panic line = %v", panicLine)
+	}
+	if stack, _ := panicLine["stack"].(string); !strings.Contains(stack, "TestRecovery") {
+		t.Errorf("panic line has no stack of the handler: %v", panicLine["stack"])
+	}
+	if requestLine["status"] != float64(http.StatusInternalServerError) || requestLine["level"] != "ERROR" {
+		t.Errorf("request line = %v, want status 500 at error level", requestLine)
+	}
+}
@@ -0,0 +1,79 @@
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yangirxd/goods-service/internal/models"
+)
+
+// Recovery перехватывает панику обработчика, пишет её в журнал вместе со
+// стеком и ID запроса и отвечает 500. Ставится после Middleware, чтобы ID
+// запроса уже был в контексте.
+func Recovery(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			// http.Server сам прерывает ответ без записи в журнал
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+
+			ctx := c.Request.Context()
+			if brokenConnection(rec) {
+				// Клиент закрыл соединение, ответ записать уже нельзя
+				logger.WarnContext(ctx, "client connection closed", "error", rec)
+				c.Abort()
+				return
+			}
+
+			logger.ErrorContext(ctx, "panic recovered",
+				"error", fmt.Sprint(rec),
+				"route", c.FullPath(),
+				"stack", string(debug.Stack()),
+			)
+			if c.Writer.Written() {
+				c.Abort()
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusInternalServerError, models.ErrorResponse{
+				Code:      2,
+				Message:   "errors.internal",
+				Details:   "internal server error",
+				RequestID: RequestID(ctx),
+			})
+		}()
+
+		c.Next()
+	}
+}
+
+// brokenConnection сообщает, что паника вызвана записью в соединение,
+// закрытое клиентом
+func brokenConnection(rec interface{}) bool {
+	err, ok := rec.(error)
+	if !ok {
+		return false
+	}
+
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) {
+		return false
+	}
+	var syscallErr *os.SyscallError
+	if !errors.As(opErr, &syscallErr) {
+		return false
+	}
+	msg := strings.ToLower(syscallErr.Error())
+	return strings.Contains(msg, "broken pipe") || strings.Contains(msg, "connection reset by peer")
+}
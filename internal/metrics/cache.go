@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/yangirxd/goods-service/internal/cache"
+)
+
+// RegisterCache публикует счётчики попаданий и промахов кэша и признак
+// деградированного режима
+func RegisterCache(c *cache.GoodsCache) {
+	requests := func(tier, result string, value func(cache.Stats) uint64) {
+		promauto.NewCounterFunc(prometheus.CounterOpts{
+			Namespace:   namespace,
+			Subsystem:   "cache",
+			Name:        "requests_total",
+			Help:        "Cache lookups by tier and result.",
+			ConstLabels: prometheus.Labels{"tier": tier, "result": result},
+		}, func() float64 {
+			return float64(value(c.Stats()))
+		})
+	}
+
+	requests("local", "hit", func(s cache.Stats) uint64 { return s.LocalHits })
+	requests("local", "miss", func(s cache.Stats) uint64 { return s.LocalMisses })
+	requests("redis", "hit", func(s cache.Stats) uint64 { return s.RedisHits })
+	requests("redis", "miss", func(s cache.Stats) uint64 { return s.RedisMisses })
+
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "cache",
+		Name:      "degraded",
+		Help:      "1 while the cache does not call Redis after repeated errors.",
+	}, func() float64 {
+		if c.Degraded() {
+			return 1
+		}
+		return 0
+	})
+}
@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "goods"
+
+var (
+	// HTTPRequestDuration время обработки HTTP-запросов по маршрутам и статусам
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency by route, method and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	// QueryDuration время выполнения операций GoodsRepository
+	QueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "repository",
+		Name:      "query_duration_seconds",
+		Help:      "GoodsRepository operation latency.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"})
+
+	// TxConflicts количество транзакций, прерванных конфликтом
+	TxConflicts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "repository",
+		Name:      "transaction_conflicts_total",
+		Help:      "Transactions aborted by a serialization failure or deadlock.",
+	}, []string{"operation"})
+
+	// PublishFailures количество событий, которые не удалось опубликовать в NATS
+	PublishFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "nats",
+		Name:      "publish_failures_total",
+		Help:      "Events that could not be published to NATS.",
+	}, []string{"action"})
+
+	// RateLimited количество запросов, отклонённых ограничением частоты
+	RateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "rate_limited_total",
+		Help:      "Requests rejected with 429 by route.",
+	}, []string{"route"})
+
+	// ClickHouseBatchSize размер записанных в ClickHouse пакетов
+	ClickHouseBatchSize = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "clickhouse",
+		Name:      "batch_size",
+		Help:      "Number of events per ClickHouse flush.",
+		Buckets:   []float64{1, 5, 10, 25, 50, 100},
+	})
+
+	// ClickHouseFlushDuration время записи пакета в ClickHouse
+	ClickHouseFlushDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "clickhouse",
+		Name:      "flush_duration_seconds",
+		Help:      "ClickHouse flush latency by result.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"status"})
+
+	// ClickHousePending количество событий, ожидающих записи в ClickHouse
+	ClickHousePending = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "clickhouse",
+		Name:      "pending_events",
+		Help:      "Events buffered and not yet written to ClickHouse.",
+	})
+)
+
+// Status возвращает метку результата операции
+func Status(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
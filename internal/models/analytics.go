@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// DailyStats содержит количество событий проекта за один день
+type DailyStats struct {
+	Date          time.Time `json:"date"`
+	ProjectID     int64     `json:"project_id"`
+	Creates       int64     `json:"creates"`
+	Updates       int64     `json:"updates"`
+	Deletes       int64     `json:"deletes"`
+	Reprioritizes int64     `json:"reprioritizes"`
+}
+
+// ReprioritizedGood содержит количество изменений приоритета товара
+type ReprioritizedGood struct {
+	GoodID            int64 `json:"good_id"`
+	ProjectID         int64 `json:"project_id"`
+	Reprioritizations int64 `json:"reprioritizations"`
+}
+
+// ChurnStats содержит отток товаров проекта за период
+type ChurnStats struct {
+	ProjectID int64 `json:"project_id"`
+	Creates   int64 `json:"creates"`
+	Deletes   int64 `json:"deletes"`
+	// ChurnRate отношение удалённых товаров к созданным за период
+	ChurnRate float64 `json:"churn_rate"`
+}
+
+// AnalyticsMeta содержит период, за который посчитана статистика
+type AnalyticsMeta struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// DailyStatsResponse представляет ответ со статистикой по дням
+type DailyStatsResponse struct {
+	Meta  AnalyticsMeta `json:"meta"`
+	Stats []DailyStats  `json:"stats"`
+}
+
+// ReprioritizedResponse представляет ответ с самыми часто переставляемыми товарами
+type ReprioritizedResponse struct {
+	Meta  AnalyticsMeta       `json:"meta"`
+	Goods []ReprioritizedGood `json:"goods"`
+}
+
+// ChurnResponse представляет ответ с оттоком товаров по проектам
+type ChurnResponse struct {
+	Meta  AnalyticsMeta `json:"meta"`
+	Stats []ChurnStats  `json:"stats"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// APIKey ключ API для доступа сервисов. Сам ключ не хранится, только его хеш.
+type APIKey struct {
+	ID     int64  `json:"id"`
+	Name   string `json:"name"`
+	Prefix string `json:"prefix"`
+	// Scopes операции, которые разрешены ключу
+	Scopes     []string   `json:"scopes"`
+	CreatedBy  string     `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	KeyHash    []byte     `json:"-"`
+}
+
+// APIKeyCreate запрос на выпуск ключа API
+type APIKeyCreate struct {
+	Name   string   `json:"name" binding:"required"`
+	Scopes []string `json:"scopes" binding:"required,min=1"`
+	// ExpiresAt срок действия ключа; без него ключ бессрочный
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// APIKeyIssued выпущенный ключ API. Key возвращается только один раз.
+type APIKeyIssued struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// APIKeyListResponse представляет ответ со списком ключей API
+type APIKeyListResponse struct {
+	Keys []APIKey `json:"keys"`
+}
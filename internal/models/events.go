@@ -0,0 +1,48 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Действия, которые записываются в журнал событий
+const (
+	ActionCreate       = "create"
+	ActionUpdate       = "update"
+	ActionDelete       = "delete"
+	ActionReprioritize = "reprioritize"
+)
+
+// IsValidAction сообщает, является ли строка известным действием
+func IsValidAction(action string) bool {
+	switch action {
+	case ActionCreate, ActionUpdate, ActionDelete, ActionReprioritize:
+		return true
+	}
+	return false
+}
+
+// GoodEvent представляет событие из истории изменений товара
+type GoodEvent struct {
+	ID        string    `json:"id"`
+	Action    string    `json:"action"`
+	Timestamp time.Time `json:"timestamp"`
+	GoodID    int64     `json:"good_id"`
+	ProjectID int64     `json:"project_id"`
+	RequestID string    `json:"request_id,omitempty"`
+	// Actor субъект, выполнивший изменение; пустой для анонимных запросов
+	Actor string          `json:"actor,omitempty"`
+	Data  json.RawMessage `json:"data" swaggertype:"object"`
+}
+
+// HistoryMeta содержит параметры выборки истории
+type HistoryMeta struct {
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+}
+
+// HistoryResponse представляет ответ с историей событий
+type HistoryResponse struct {
+	Meta   HistoryMeta `json:"meta"`
+	Events []GoodEvent `json:"events"`
+}
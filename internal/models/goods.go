@@ -38,9 +38,10 @@ type GoodUpdate struct {
 }
 
 type ErrorResponse struct {
-	Code    int         `json:"code"`
-	Message string      `json:"message"`
-	Details interface{} `json:"details"`
+	Code      int         `json:"code"`
+	Message   string      `json:"message"`
+	Details   interface{} `json:"details"`
+	RequestID string      `json:"request_id,omitempty"`
 }
 
 // ReprioritizeRequest представляет запрос на изменение приоритета
@@ -0,0 +1,24 @@
+package models
+
+// HealthResponse ответ проверки живости процесса
+type HealthResponse struct {
+	Status string `json:"status"`
+}
+
+// DependencyStatus результат проверки одной зависимости
+type DependencyStatus struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	Critical  bool    `json:"critical"`
+	LatencyMs float64 `json:"latency_ms"`
+	// Degraded сервис обходит зависимость, например кэш не обращается к Redis
+	Degraded bool   `json:"degraded,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// ReadinessResponse ответ проверки готовности. Status равен down, если
+// недоступна хотя бы одна критичная зависимость.
+type ReadinessResponse struct {
+	Status       string             `json:"status"`
+	Dependencies []DependencyStatus `json:"dependencies"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// ProjectMember роль субъекта в проекте
+type ProjectMember struct {
+	ProjectID int64  `json:"project_id"`
+	Subject   string `json:"subject"`
+	// Role одна из viewer, editor, admin
+	Role      string    `json:"role"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ProjectMemberUpdate запрос на назначение роли в проекте
+type ProjectMemberUpdate struct {
+	Role string `json:"role" binding:"required,oneof=viewer editor admin"`
+}
+
+// ProjectMemberListResponse представляет ответ со списком участников проекта
+type ProjectMemberListResponse struct {
+	Members []ProjectMember `json:"members"`
+}
@@ -0,0 +1,82 @@
+package queue
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+const (
+	dedupWindow = 10 * time.Minute
+	dedupSize   = 100000
+)
+
+type seenEntry struct {
+	id     string
+	seenAt time.Time
+}
+
+// seenIDs хранит идентификаторы недавно обработанных событий.
+// Запись вытесняется по истечении окна или при превышении размера.
+type seenIDs struct {
+	mu      sync.Mutex
+	window  time.Duration
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+func newSeenIDs(window time.Duration, size int) *seenIDs {
+	return &seenIDs{
+		window:  window,
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element, size),
+	}
+}
+
+// Seen сообщает, встречался ли идентификатор в пределах окна,
+// и запоминает его, если не встречался
+func (s *seenIDs) Seen(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.evict(now)
+
+	if _, ok := s.entries[id]; ok {
+		return true
+	}
+
+	s.entries[id] = s.order.PushBack(seenEntry{id: id, seenAt: now})
+	if s.order.Len() > s.size {
+		s.remove(s.order.Front())
+	}
+	return false
+}
+
+// Forget удаляет идентификатор, чтобы повторная доставка события
+// была обработана заново
+func (s *seenIDs) Forget(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[id]; ok {
+		s.remove(e)
+	}
+}
+
+// evict удаляет записи старше окна
+func (s *seenIDs) evict(now time.Time) {
+	for e := s.order.Front(); e != nil; e = s.order.Front() {
+		if now.Sub(e.Value.(seenEntry).seenAt) < s.window {
+			return
+		}
+		s.remove(e)
+	}
+}
+
+func (s *seenIDs) remove(e *list.Element) {
+	s.order.Remove(e)
+	delete(s.entries, e.Value.(seenEntry).id)
+}
@@ -0,0 +1,38 @@
+package queue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSeenIDs(t *testing.T) {
+	s := newSeenIDs(time.Hour, 2)
+
+	if s.Seen("a") || s.Seen("b") {
+		t.Fatal("Seen() = true for new IDs")
+	}
+	if !s.Seen("a") {
+		t.Error("Seen(a) = false for a repeated ID")
+	}
+
+	// Третий идентификатор вытесняет самый старый
+	s.Seen("c")
+	if s.Seen("a") {
+		t.Error("Seen(a) = true after it was evicted by size")
+	}
+
+	s.Forget("c")
+	if s.Seen("c") {
+		t.Error("Seen(c) = true after Forget")
+	}
+}
+
+func TestSeenIDsWindow(t *testing.T) {
+	s := newSeenIDs(time.Millisecond, 10)
+
+	s.Seen("a")
+	time.Sleep(2 * time.Millisecond)
+	if s.Seen("a") {
+		t.Error("Seen(a) = true after the window expired")
+	}
+}
@@ -1,39 +1,100 @@
 package queue
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"sync"
 	"time"
 
 	"github.com/nats-io/nats.go"
+	"github.com/yangirxd/goods-service/internal/auth"
 	"github.com/yangirxd/goods-service/internal/clickhouse"
+	"github.com/yangirxd/goods-service/internal/events"
+	"github.com/yangirxd/goods-service/internal/logging"
+	"github.com/yangirxd/goods-service/internal/metrics"
+	"github.com/yangirxd/goods-service/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
+const (
+	// SubjectAll подписка на события всех проектов и действий
+	SubjectAll = "goods.events.>"
+	// LegacySubject единый subject, в который события публиковались раньше
+	LegacySubject = "goods.logs"
+)
+
+// Subject возвращает subject события вида goods.events.<project_id>.<action>
+func Subject(projectID int64, action string) string {
+	return fmt.Sprintf("goods.events.%d.%s", projectID, action)
+}
+
 type Logger struct {
-	nc *nats.Conn
+	nc            *nats.Conn
+	publishLegacy bool
 }
 
 type LogConsumer struct {
-	nc     *nats.Conn
-	ch     *clickhouse.Client
-	stopCh chan struct{}
+	nc         *nats.Conn
+	queueGroup string
+	ch         eventWriter
+	seen       *seenIDs
+	stopCh     chan struct{}
+	stopOnce   sync.Once
+	logger     *slog.Logger
 }
 
-func NewLogger(url string) (*Logger, error) {
+// NewLogger создаёт издателя событий. Если publishLegacy выставлен, события
+// дополнительно публикуются в LegacySubject в прежнем формате на время
+// миграции подписчиков.
+func NewLogger(url string, publishLegacy bool) (*Logger, error) {
 	nc, err := nats.Connect(url)
 	if err != nil {
 		return nil, fmt.Errorf("connect to nats: %w", err)
 	}
 
-	return &Logger{nc: nc}, nil
+	return &Logger{
+		nc:            nc,
+		publishLegacy: publishLegacy,
+	}, nil
 }
 
-func (l *Logger) Log(action string, entityID int64, data interface{}) error {
-	event := clickhouse.LogEvent{
-		Action:    action,
-		Timestamp: time.Now(),
-		EntityID:  entityID,
-		Data:      data,
+// Log публикует событие в формате CloudEvents. Контекст трассировки из ctx
+// передаётся подписчикам в заголовках сообщения.
+func (l *Logger) Log(ctx context.Context, event *events.Event) (err error) {
+	action, ok := event.Action()
+	if !ok {
+		return fmt.Errorf("unknown event type: %s", event.Type)
+	}
+
+	subject := Subject(event.ProjectID, action)
+	ctx, span := tracing.Tracer.Start(ctx, "publish "+subject,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "nats"),
+			attribute.String("messaging.destination.name", subject),
+			attribute.String("messaging.message.id", event.ID),
+		),
+	)
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	if event.RequestID == "" {
+		event.RequestID = logging.RequestID(ctx)
+	}
+	if event.Actor == "" {
+		event.Actor = auth.Subject(ctx)
 	}
 
 	payload, err := json.Marshal(event)
@@ -41,35 +102,92 @@ func (l *Logger) Log(action string, entityID int64, data interface{}) error {
 		return fmt.Errorf("marshal event: %w", err)
 	}
 
-	err = l.nc.Publish("goods.logs", payload)
+	header := nats.Header{}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+
+	err = l.nc.PublishMsg(&nats.Msg{Subject: subject, Data: payload, Header: header})
 	if err != nil {
+		metrics.PublishFailures.WithLabelValues(action).Inc()
 		return fmt.Errorf("publish event: %w", err)
 	}
 
+	if l.publishLegacy {
+		legacy, err := json.Marshal(newLegacyEvent(event, action))
+		if err != nil {
+			return fmt.Errorf("marshal legacy event: %w", err)
+		}
+		err = l.nc.PublishMsg(&nats.Msg{Subject: LegacySubject, Data: legacy, Header: header})
+		if err != nil {
+			metrics.PublishFailures.WithLabelValues(action).Inc()
+			return fmt.Errorf("publish legacy event: %w", err)
+		}
+	}
+
 	return nil
 }
 
+// legacyEvent формат сообщений в LegacySubject, который публиковался до
+// перехода на CloudEvents
+type legacyEvent struct {
+	ID        string      `json:"id"`
+	Action    string      `json:"action"`
+	Timestamp time.Time   `json:"timestamp"`
+	EntityID  int64       `json:"entity_id"`
+	ProjectID int64       `json:"project_id"`
+	Data      interface{} `json:"data"`
+}
+
+func newLegacyEvent(event *events.Event, action string) *legacyEvent {
+	// Subject событий, созданных сервисом, всегда содержит ID товара
+	entityID, _ := event.GoodID()
+	return &legacyEvent{
+		ID:        event.ID,
+		Action:    action,
+		Timestamp: event.Time,
+		EntityID:  entityID,
+		ProjectID: event.ProjectID,
+		Data:      event.LegacyData(),
+	}
+}
+
+// Ping возвращает ошибку, если соединение с NATS не установлено
+func (l *Logger) Ping() error {
+	return connStatus(l.nc)
+}
+
+// Drain дожидается отправки опубликованных событий и закрывает соединение
+func (l *Logger) Drain(ctx context.Context) error {
+	return drain(ctx, l.nc)
+}
+
 func (l *Logger) Close() {
 	l.nc.Close()
 }
 
-// NewLogConsumer создает новый экземпляр потребителя логов
-func NewLogConsumer(natsURL, clickhouseURL string) (*LogConsumer, error) {
-	nc, err := nats.Connect(natsURL)
-	if err != nil {
-		return nil, fmt.Errorf("подключение к NATS: %w", err)
-	}
+// eventWriter записывает события пакетами, например clickhouse.Client
+type eventWriter interface {
+	Start()
+	Stop() error
+	AddEvent(event *clickhouse.LogEvent) error
+}
 
-	ch, err := clickhouse.NewClient(clickhouseURL)
+// NewLogConsumer создает новый экземпляр потребителя логов. Потребители
+// с одинаковой queueGroup делят сообщения между собой: каждое событие
+// получает только один из них.
+// Клиент ClickHouse принадлежит вызывающей стороне и не закрывается в Close.
+func NewLogConsumer(natsURL, queueGroup string, ch *clickhouse.Client, logger *slog.Logger) (*LogConsumer, error) {
+	nc, err := nats.Connect(natsURL)
 	if err != nil {
-		nc.Close()
-		return nil, fmt.Errorf("подключение к ClickHouse: %w", err)
+		return nil, fmt.Errorf("connect to nats: %w", err)
 	}
 
 	return &LogConsumer{
-		nc:     nc,
-		ch:     ch,
-		stopCh: make(chan struct{}),
+		nc:         nc,
+		queueGroup: queueGroup,
+		ch:         ch,
+		seen:       newSeenIDs(dedupWindow, dedupSize),
+		stopCh:     make(chan struct{}),
+		logger:     logger,
 	}, nil
 }
 
@@ -77,19 +195,9 @@ func NewLogConsumer(natsURL, clickhouseURL string) (*LogConsumer, error) {
 func (c *LogConsumer) Start() error {
 	c.ch.Start()
 
-	sub, err := c.nc.Subscribe("goods.logs", func(msg *nats.Msg) {
-		var event clickhouse.LogEvent
-		if err := json.Unmarshal(msg.Data, &event); err != nil {
-			fmt.Printf("Ошибка разбора сообщения: %v\n", err)
-			return
-		}
-
-		if err := c.ch.AddEvent(&event); err != nil {
-			fmt.Printf("Ошибка добавления события: %v\n", err)
-		}
-	})
+	sub, err := c.nc.QueueSubscribe(SubjectAll, c.queueGroup, c.handle)
 	if err != nil {
-		return fmt.Errorf("ошибка подписки на goods.logs: %w", err)
+		return fmt.Errorf("subscribe to %s: %w", SubjectAll, err)
 	}
 
 	<-c.stopCh
@@ -97,17 +205,133 @@ func (c *LogConsumer) Start() error {
 	return nil
 }
 
+// handle записывает событие из сообщения в ClickHouse. Span обработки
+// продолжает трассировку, начатую при публикации события.
+func (c *LogConsumer) handle(msg *nats.Msg) {
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), propagation.HeaderCarrier(msg.Header))
+	ctx, span := tracing.Tracer.Start(ctx, "process "+msg.Subject,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "nats"),
+			attribute.String("messaging.destination.name", msg.Subject),
+		),
+	)
+	defer span.End()
+
+	var event events.Event
+	if err := json.Unmarshal(msg.Data, &event); err != nil {
+		span.RecordError(err)
+		c.logger.ErrorContext(ctx, "unmarshal event", "subject", msg.Subject, "error", err)
+		return
+	}
+	ctx = logging.WithRequestID(ctx, event.RequestID)
+
+	logEvent, err := toLogEvent(&event)
+	if err != nil {
+		span.RecordError(err)
+		c.logger.ErrorContext(ctx, "invalid event", "event_id", event.ID, "error", err)
+		return
+	}
+	span.SetAttributes(attribute.String("messaging.message.id", logEvent.ID))
+
+	// Повторно доставленные события отбрасываются. Если событие не удалось
+	// записать, его идентификатор забывается, чтобы повторная доставка не
+	// была принята за копию.
+	if c.seen.Seen(logEvent.ID) {
+		span.SetAttributes(attribute.Bool("duplicate", true))
+		return
+	}
+
+	logEvent.Trace = span.SpanContext()
+	if err := c.ch.AddEvent(logEvent); err != nil {
+		span.RecordError(err)
+		c.logger.ErrorContext(ctx, "add event to clickhouse batch", "event_id", logEvent.ID, "error", err)
+		c.seen.Forget(logEvent.ID)
+	}
+}
+
+// toLogEvent преобразует событие CloudEvents в запись журнала ClickHouse
+func toLogEvent(event *events.Event) (*clickhouse.LogEvent, error) {
+	if event.SpecVersion != events.SpecVersion {
+		return nil, fmt.Errorf("unsupported cloudevents version %q", event.SpecVersion)
+	}
+	if event.ID == "" {
+		return nil, errors.New("event has no id")
+	}
+
+	action, ok := event.Action()
+	if !ok {
+		return nil, fmt.Errorf("unknown event type %q", event.Type)
+	}
+
+	goodID, err := event.GoodID()
+	if err != nil {
+		return nil, fmt.Errorf("invalid subject: %w", err)
+	}
+
+	return &clickhouse.LogEvent{
+		ID:        event.ID,
+		Action:    action,
+		Timestamp: event.Time,
+		EntityID:  goodID,
+		ProjectID: event.ProjectID,
+		RequestID: event.RequestID,
+		Actor:     event.Actor,
+		Data:      event.Data,
+	}, nil
+}
+
 // Stop останавливает обработку и записывает оставшиеся логи
 func (c *LogConsumer) Stop() error {
-	close(c.stopCh)
+	c.stopOnce.Do(func() { close(c.stopCh) })
 	return c.ch.Stop()
 }
 
-// Close закрывает все соединения
-func (c *LogConsumer) Close() error {
+// Shutdown прекращает приём сообщений, дожидается обработки уже полученных
+// и записывает оставшиеся события в ClickHouse
+func (c *LogConsumer) Shutdown(ctx context.Context) error {
+	drainErr := drain(ctx, c.nc)
 	if err := c.Stop(); err != nil {
 		return err
 	}
+	return drainErr
+}
+
+// Ping возвращает ошибку, если соединение с NATS не установлено
+func (c *LogConsumer) Ping() error {
+	return connStatus(c.nc)
+}
+
+// Close останавливает обработку и закрывает соединение с NATS
+func (c *LogConsumer) Close() error {
+	err := c.Stop()
 	c.nc.Close()
-	return c.ch.Close()
+	return err
+}
+
+func connStatus(nc *nats.Conn) error {
+	if status := nc.Status(); status != nats.CONNECTED {
+		return fmt.Errorf("nats connection is %s", status)
+	}
+	return nil
+}
+
+// drain отписывается от subject, дожидается обработки полученных сообщений
+// и отправки опубликованных, после чего закрывает соединение. Если ctx
+// истекает раньше, соединение закрывается сразу.
+func drain(ctx context.Context, nc *nats.Conn) error {
+	closed := make(chan struct{})
+	nc.SetClosedHandler(func(*nats.Conn) { close(closed) })
+
+	if err := nc.Drain(); err != nil {
+		return fmt.Errorf("drain nats: %w", err)
+	}
+
+	select {
+	case <-closed:
+		return nil
+	case <-ctx.Done():
+		nc.Close()
+		return fmt.Errorf("drain nats: %w", ctx.Err())
+	}
 }
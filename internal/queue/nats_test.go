@@ -0,0 +1,176 @@
+package queue
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/yangirxd/goods-service/internal/clickhouse"
+	"github.com/yangirxd/goods-service/internal/events"
+	"github.com/yangirxd/goods-service/internal/models"
+)
+
+// fakeWriter запоминает принятые события и возвращает err из AddEvent
+type fakeWriter struct {
+	added []*clickhouse.LogEvent
+	err   error
+}
+
+func (w *fakeWriter) Start()      {}
+func (w *fakeWriter) Stop() error { return nil }
+
+func (w *fakeWriter) AddEvent(event *clickhouse.LogEvent) error {
+	if w.err != nil {
+		return w.err
+	}
+	w.added = append(w.added, event)
+	return nil
+}
+
+func newTestConsumer(w *fakeWriter) *LogConsumer {
+	return &LogConsumer{
+		ch:     w,
+		seen:   newSeenIDs(dedupWindow, dedupSize),
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		stopCh: make(chan struct{}),
+	}
+}
+
+func eventMsg(t *testing.T, event *events.Event) *nats.Msg {
+	t.Helper()
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &nats.Msg{Subject: Subject(event.ProjectID, "create"), Data: data}
+}
+
+func TestHandleDropsRedeliveredEvents(t *testing.T) {
+	w := &fakeWriter{}
+	c := newTestConsumer(w)
+	msg := eventMsg(t, events.GoodCreated(&models.Good{ID: 1, ProjectID: 2, Name: "a"}))
+
+	c.handle(msg)
+	c.handle(msg)
+
+	if len(w.added) != 1 {
+		t.Fatalf("AddEvent() called %d times, want 1", len(w.added))
+	}
+	if got := w.added[0]; got.EntityID != 1 || got.ProjectID != 2 || got.Action != "create" {
+		t.Errorf("AddEvent() got %+v", got)
+	}
+}
+
+func TestHandleAcceptsRedeliveryAfterFailedWrite(t *testing.T) {
+	w := &fakeWriter{err: errors.New("clickhouse is down")}
+	c := newTestConsumer(w)
+	event := events.GoodCreated(&models.Good{ID: 1, ProjectID: 2, Name: "a"})
+	msg := eventMsg(t, event)
+
+	c.handle(msg)
+
+	w.err = nil
+	c.handle(msg)
+
+	if len(w.added) != 1 || w.added[0].ID != event.ID {
+		t.Fatalf("redelivered event was not written: %+v", w.added)
+	}
+
+	// После успешной записи копии снова отбрасываются
+	c.handle(msg)
+	if len(w.added) != 1 {
+		t.Errorf("AddEvent() called %d times after a duplicate, want 1", len(w.added))
+	}
+}
+
+func TestStopTwice(t *testing.T) {
+	c := newTestConsumer(&fakeWriter{})
+
+	for i := 0; i < 2; i++ {
+		if err := c.Stop(); err != nil {
+			t.Fatalf("Stop() error = %v", err)
+		}
+	}
+
+	select {
+	case <-c.stopCh:
+	default:
+		t.Error("stopCh is not closed after Stop")
+	}
+}
+
+func TestToLogEvent(t *testing.T) {
+	valid := events.GoodUpdated(&models.Good{ID: 7, ProjectID: 3, Name: "a"})
+
+	got, err := toLogEvent(valid)
+	if err != nil {
+		t.Fatalf("toLogEvent() error = %v", err)
+	}
+	if got.ID != valid.ID || got.Action != "update" || got.EntityID != 7 || got.ProjectID != 3 || !got.Timestamp.Equal(valid.Time) {
+		t.Errorf("toLogEvent() = %+v", got)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(e *events.Event)
+	}{
+		{"other spec version", func(e *events.Event) { e.SpecVersion = "0.3" }},
+		{"no id", func(e *events.Event) { e.ID = "" }},
+		{"unknown type", func(e *events.Event) { e.Type = "goods.good.archived.v1" }},
+		{"subject is not a good id", func(e *events.Event) { e.Subject = "goods/7" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := *valid
+			tt.modify(&event)
+			if got, err := toLogEvent(&event); err == nil {
+				t.Errorf("toLogEvent() = %+v, want an error", got)
+			}
+		})
+	}
+}
+
+func TestLegacyEvent(t *testing.T) {
+	good := &models.Good{ID: 7, ProjectID: 3, Name: "a", Priority: 2}
+
+	tests := []struct {
+		name   string
+		event  *events.Event
+		action string
+		data   string
+	}{
+		{"create", events.GoodCreated(good), "create", `{"id":7,"project_id":3,"name":"a","priority":2,"removed":false,"created_at":"0001-01-01T00:00:00Z"}`},
+		{"delete", events.GoodDeleted(good), "delete", `null`},
+		{"reprioritize", events.GoodReprioritized(7, 3, 2, []*models.Good{good}), "reprioritize",
+			`{"new_priority":2,"project_id":3,"updated_ids":[{"id":7,"project_id":3,"name":"a","priority":2,"removed":false,"created_at":"0001-01-01T00:00:00Z"}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, err := json.Marshal(newLegacyEvent(tt.event, tt.action))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got map[string]json.RawMessage
+			if err := json.Unmarshal(payload, &got); err != nil {
+				t.Fatal(err)
+			}
+			want := []string{"id", "action", "timestamp", "entity_id", "project_id", "data"}
+			if len(got) != len(want) {
+				t.Errorf("legacy event fields = %s, want %v", payload, want)
+			}
+			if string(got["action"]) != `"`+tt.action+`"` || string(got["entity_id"]) != "7" || string(got["project_id"]) != "3" {
+				t.Errorf("legacy event = %s", payload)
+			}
+			if string(got["data"]) != tt.data {
+				t.Errorf("data = %s, want %s", got["data"], tt.data)
+			}
+		})
+	}
+}
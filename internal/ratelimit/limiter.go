@@ -0,0 +1,141 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Limit параметры ведра токенов
+type Limit struct {
+	// Rate сколько токенов в секунду пополняется ведро, то есть
+	// устойчивое число запросов в секунду
+	Rate float64 `yaml:"rate"`
+	// Burst ёмкость ведра: сколько запросов можно сделать подряд
+	Burst int `yaml:"burst"`
+}
+
+// Config настройки ограничения частоты запросов к API
+type Config struct {
+	Enabled bool `yaml:"enabled"`
+	// Default ограничение маршрутов, для которых не задано своё
+	Default Limit `yaml:"default"`
+	// Routes ограничения маршрутов по ключу "METHOD /шаблон/пути",
+	// например "PATCH /goods/reprioritize". Нулевой Rate снимает ограничение.
+	Routes map[string]Limit `yaml:"routes"`
+	// Timeout сколько ждать ответа Redis, прежде чем пропустить запрос без
+	// ограничения. Короткий, чтобы медленный Redis не задерживал API.
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Default: Limit{Rate: 20, Burst: 40},
+		Timeout: 50 * time.Millisecond,
+		Routes: map[string]Limit{
+			// Каждый вызов — SERIALIZABLE-транзакция над всем проектом
+			"PATCH /goods/reprioritize": {Rate: 1, Burst: 5},
+		},
+	}
+}
+
+// route возвращает ограничение маршрута или false, если его нет
+func (c Config) route(route string) (Limit, bool) {
+	limit, ok := c.Routes[route]
+	if !ok {
+		limit = c.Default
+	}
+	return limit, limit.Rate > 0 && limit.Burst > 0
+}
+
+// Result решение по запросу
+type Result struct {
+	Allowed bool
+	// Remaining сколько запросов ещё можно сделать подряд
+	Remaining int
+	// Reset через сколько ведро наполнится целиком
+	Reset time.Duration
+	// RetryAfter через сколько появится токен, если запрос отклонён
+	RetryAfter time.Duration
+}
+
+// takeScript забирает токен из ведра KEYS[1] с пополнением ARGV[1] токенов
+// в секунду и ёмкостью ARGV[2]. Время берётся у Redis, чтобы расхождение
+// часов реплик сервиса не влияло на пополнение. Возвращает признак
+// разрешения, остаток токенов, время до полного ведра и до следующего
+// токена в миллисекундах.
+var takeScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate / 1000)
+
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry = math.ceil((1 - tokens) * 1000 / rate)
+end
+local reset = math.ceil((burst - tokens) * 1000 / rate)
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], reset + 1000)
+return {allowed, math.floor(tokens), reset, retry}
+`)
+
+// ErrUnavailable означает, что Redis считается недоступным и к нему не обращались
+var ErrUnavailable = errors.New("rate limiter is unavailable")
+
+// Limiter ограничивает частоту запросов ведрами токенов в Redis, поэтому
+// ограничения общие для всех реплик сервиса
+type Limiter struct {
+	client redis.UniversalClient
+	cfg    Config
+	// degraded сообщает, что Redis сейчас недоступен, например
+	// GoodsCache.Degraded: тогда запросы пропускаются без обращения к нему
+	degraded func() bool
+}
+
+// NewLimiter создаёт ограничитель. degraded может быть nil.
+func NewLimiter(client redis.UniversalClient, cfg Config, degraded func() bool) *Limiter {
+	return &Limiter{client: client, cfg: cfg, degraded: degraded}
+}
+
+// Take забирает токен из ведра клиента client на маршруте route. Ожидание
+// Redis ограничено Config.Timeout.
+func (l *Limiter) Take(ctx context.Context, route, client string, limit Limit) (*Result, error) {
+	if l.degraded != nil && l.degraded() {
+		return nil, ErrUnavailable
+	}
+	if l.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, l.cfg.Timeout)
+		defer cancel()
+	}
+
+	key := "ratelimit:" + route + ":" + client
+	values, err := takeScript.Run(ctx, l.client, []string{key}, limit.Rate, limit.Burst).Int64Slice()
+	if err != nil {
+		return nil, fmt.Errorf("take token: %w", err)
+	}
+	if len(values) != 4 {
+		return nil, fmt.Errorf("take token: unexpected reply %v", values)
+	}
+
+	return &Result{
+		Allowed:    values[0] == 1,
+		Remaining:  int(values[1]),
+		Reset:      time.Duration(values[2]) * time.Millisecond,
+		RetryAfter: time.Duration(values[3]) * time.Millisecond,
+	}, nil
+}
@@ -0,0 +1,186 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+var testNow = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// newTestLimiter возвращает ограничитель поверх miniredis, в котором
+// команда TIME отдаёт testNow, пока тест не сдвинет время
+func newTestLimiter(t *testing.T, cfg Config) (*Limiter, *miniredis.Miniredis) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	server.SetTime(testNow)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return NewLimiter(client, cfg, nil), server
+}
+
+func take(t *testing.T, l *Limiter, client string, limit Limit) *Result {
+	t.Helper()
+
+	result, err := l.Take(context.Background(), "GET /goods/list", client, limit)
+	if err != nil {
+		t.Fatalf("Take() error = %v", err)
+	}
+	return result
+}
+
+func TestTakeBurst(t *testing.T) {
+	l, _ := newTestLimiter(t, DefaultConfig())
+	limit := Limit{Rate: 1, Burst: 3}
+
+	for want := 2; want >= 0; want-- {
+		result := take(t, l, "a", limit)
+		if !result.Allowed || result.Remaining != want {
+			t.Fatalf("Take() = %+v, want allowed with %d remaining", result, want)
+		}
+	}
+
+	result := take(t, l, "a", limit)
+	if result.Allowed {
+		t.Fatalf("Take() allowed a request over the burst: %+v", result)
+	}
+	if result.Remaining != 0 || result.RetryAfter != time.Second || result.Reset != 3*time.Second {
+		t.Errorf("Take() = %+v, want 0 remaining, retry after 1s, reset in 3s", result)
+	}
+
+	// У другого клиента своё ведро
+	if result := take(t, l, "b", limit); !result.Allowed || result.Remaining != 2 {
+		t.Errorf("Take() for another client = %+v, want a full bucket", result)
+	}
+}
+
+func TestTakeRefill(t *testing.T) {
+	l, server := newTestLimiter(t, DefaultConfig())
+	limit := Limit{Rate: 2, Burst: 2}
+
+	take(t, l, "a", limit)
+	take(t, l, "a", limit)
+	if result := take(t, l, "a", limit); result.Allowed {
+		t.Fatalf("Take() allowed a request from an empty bucket: %+v", result)
+	}
+
+	// За 750 мс при 2 токенах в секунду набирается полтора токена
+	server.SetTime(testNow.Add(750 * time.Millisecond))
+	result := take(t, l, "a", limit)
+	if !result.Allowed || result.Remaining != 0 {
+		t.Fatalf("Take() = %+v, want allowed with 0 remaining", result)
+	}
+	if result.Reset != 750*time.Millisecond {
+		t.Errorf("Reset = %v, want 750ms", result.Reset)
+	}
+
+	result = take(t, l, "a", limit)
+	if result.Allowed || result.RetryAfter != 250*time.Millisecond {
+		t.Errorf("Take() = %+v, want rejected with retry after 250ms", result)
+	}
+
+	// Ведро не переполняется сверх burst
+	server.SetTime(testNow.Add(time.Minute))
+	if result := take(t, l, "a", limit); result.Remaining != 1 {
+		t.Errorf("Take() after a long pause = %+v, want 1 remaining", result)
+	}
+}
+
+func TestTakeSkipsDegradedRedis(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1"})
+	t.Cleanup(func() { client.Close() })
+	l := NewLimiter(client, DefaultConfig(), func() bool { return true })
+
+	_, err := l.Take(context.Background(), "GET /goods/list", "a", Limit{Rate: 1, Burst: 1})
+	if !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Take() error = %v, want %v", err, ErrUnavailable)
+	}
+}
+
+func TestTakeTimeout(t *testing.T) {
+	// Сервер принимает соединения и ничего не отвечает
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { conn.Close() })
+		}
+	}()
+
+	client := redis.NewClient(&redis.Options{Addr: listener.Addr().String(), ContextTimeoutEnabled: true})
+	t.Cleanup(func() { client.Close() })
+	cfg := DefaultConfig()
+	cfg.Timeout = 50 * time.Millisecond
+	l := NewLimiter(client, cfg, nil)
+
+	start := time.Now()
+	if _, err := l.Take(context.Background(), "GET /goods/list", "a", Limit{Rate: 1, Burst: 1}); err == nil {
+		t.Fatal("Take() succeeded without a reply from Redis")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Take() waited %v, want about %v", elapsed, cfg.Timeout)
+	}
+}
+
+func TestMiddlewareHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := DefaultConfig()
+	cfg.Default = Limit{Rate: 1, Burst: 2}
+	l, _ := newTestLimiter(t, cfg)
+
+	r := gin.New()
+	r.GET("/goods/list", l.Middleware(nil), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	request := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/goods/list", nil))
+		return w
+	}
+
+	w := request()
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	for header, want := range map[string]string{
+		"RateLimit-Policy":    "2;w=2",
+		"RateLimit-Limit":     "2",
+		"RateLimit-Remaining": "1",
+		"RateLimit-Reset":     "1",
+	} {
+		if got := w.Header().Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+
+	request()
+	w = request()
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429", w.Code)
+	}
+	for header, want := range map[string]string{
+		"RateLimit-Remaining": "0",
+		"RateLimit-Reset":     "2",
+		"Retry-After":         "1",
+	} {
+		if got := w.Header().Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package ratelimit
+
+import (
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yangirxd/goods-service/internal/auth"
+	"github.com/yangirxd/goods-service/internal/logging"
+	"github.com/yangirxd/goods-service/internal/metrics"
+	"github.com/yangirxd/goods-service/internal/models"
+)
+
+// Middleware ограничивает частоту запросов каждого клиента к маршруту.
+// Клиент — субъект запроса (ключ API или claim sub), а без аутентификации —
+// IP-адрес, поэтому Middleware ставится после auth.Middleware. Ответы
+// содержат заголовки RateLimit-*, отклонённые запросы получают 429.
+// Если Redis недоступен или не ответил за Config.Timeout, запросы
+// пропускаются без ограничения.
+func (l *Limiter) Middleware(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.Request.Method + " " + c.FullPath()
+		limit, ok := l.cfg.route(route)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		ctx := c.Request.Context()
+		result, err := l.Take(ctx, route, client(c), limit)
+		if errors.Is(err, ErrUnavailable) {
+			c.Next()
+			return
+		}
+		if err != nil {
+			logger.WarnContext(ctx, "check rate limit", "route", route, "error", err)
+			c.Next()
+			return
+		}
+
+		// Окно политики — время, за которое пустое ведро наполняется целиком
+		window := time.Duration(float64(limit.Burst) / limit.Rate * float64(time.Second))
+		header := c.Writer.Header()
+		header.Set("RateLimit-Policy", strconv.Itoa(limit.Burst)+";w="+seconds(window))
+		header.Set("RateLimit-Limit", strconv.Itoa(limit.Burst))
+		header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		header.Set("RateLimit-Reset", seconds(result.Reset))
+
+		if !result.Allowed {
+			metrics.RateLimited.WithLabelValues(c.FullPath()).Inc()
+			header.Set("Retry-After", seconds(result.RetryAfter))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, models.ErrorResponse{
+				Code:      6,
+				Message:   "errors.rateLimit.exceeded",
+				Details:   "retry after " + seconds(result.RetryAfter) + "s",
+				RequestID: logging.RequestID(ctx),
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// client возвращает ключ клиента для ведра
+func client(c *gin.Context) string {
+	if subject := auth.Subject(c.Request.Context()); subject != "" {
+		return "subject:" + subject
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// seconds округляет длительность вверх до целых секунд
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/yangirxd/goods-service/internal/models"
+)
+
+// Столбцы api_keys в порядке scanAPIKey. Scopes не содержат пробелов,
+// поэтому читаются одной строкой через пробел.
+const apiKeyColumns = `id, name, prefix, key_hash, array_to_string(scopes, ' '), created_by,
+	created_at, expires_at, revoked_at, last_used_at`
+
+type APIKeyRepository struct {
+	db *sql.DB
+}
+
+func NewAPIKeyRepository(db *sql.DB) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+// Create сохраняет ключ с уже вычисленными prefix и хешем
+func (r *APIKeyRepository) Create(ctx context.Context, key *models.APIKey) (*models.APIKey, error) {
+	ctx, done := track(ctx, "api_key_create")
+	defer done()
+
+	row := r.db.QueryRowContext(ctx, `
+		INSERT INTO api_keys (name, prefix, key_hash, scopes, created_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+apiKeyColumns,
+		key.Name, key.Prefix, key.KeyHash, key.Scopes, key.CreatedBy, key.ExpiresAt)
+
+	created, err := scanAPIKey(row)
+	if err != nil {
+		return nil, fmt.Errorf("insert api key: %w", err)
+	}
+	return created, nil
+}
+
+// GetByPrefix возвращает ключ по его открытой части, в том числе
+// отозванный или истёкший, или nil, если ключа нет
+func (r *APIKeyRepository) GetByPrefix(ctx context.Context, prefix string) (*models.APIKey, error) {
+	ctx, done := track(ctx, "api_key_get")
+	defer done()
+
+	row := r.db.QueryRowContext(ctx, `
+		SELECT `+apiKeyColumns+`
+		FROM api_keys
+		WHERE prefix = $1
+	`, prefix)
+
+	key, err := scanAPIKey(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("select api key: %w", err)
+	}
+	return key, nil
+}
+
+// List возвращает все ключи, новые первыми
+func (r *APIKeyRepository) List(ctx context.Context) ([]*models.APIKey, error) {
+	ctx, done := track(ctx, "api_key_list")
+	defer done()
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+apiKeyColumns+`
+		FROM api_keys
+		ORDER BY id DESC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("select api keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []*models.APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan api key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate api keys: %w", err)
+	}
+	return keys, nil
+}
+
+// Revoke отзывает ключ и возвращает его или nil, если ключа нет.
+// Повторный отзыв не меняет время первого.
+func (r *APIKeyRepository) Revoke(ctx context.Context, id int64) (*models.APIKey, error) {
+	ctx, done := track(ctx, "api_key_revoke")
+	defer done()
+
+	row := r.db.QueryRowContext(ctx, `
+		UPDATE api_keys
+		SET revoked_at = COALESCE(revoked_at, CURRENT_TIMESTAMP)
+		WHERE id = $1
+		RETURNING `+apiKeyColumns,
+		id)
+
+	key, err := scanAPIKey(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("revoke api key: %w", err)
+	}
+	return key, nil
+}
+
+// TouchLastUsed отмечает использование ключа. Время обновляется не чаще
+// раза в минуту, чтобы каждый запрос не приводил к записи в базу.
+func (r *APIKeyRepository) TouchLastUsed(ctx context.Context, id int64) error {
+	ctx, done := track(ctx, "api_key_touch")
+	defer done()
+
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE api_keys
+		SET last_used_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < CURRENT_TIMESTAMP - INTERVAL '1 minute')
+	`, id)
+	if err != nil {
+		return fmt.Errorf("update api key last use: %w", err)
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAPIKey(row rowScanner) (*models.APIKey, error) {
+	var (
+		key    models.APIKey
+		scopes string
+	)
+	err := row.Scan(&key.ID, &key.Name, &key.Prefix, &key.KeyHash, &scopes, &key.CreatedBy,
+		&key.CreatedAt, &key.ExpiresAt, &key.RevokedAt, &key.LastUsedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	key.Scopes = strings.Fields(scopes)
+	return &key, nil
+}
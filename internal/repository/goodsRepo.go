@@ -5,10 +5,24 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/yangirxd/goods-service/internal/auth"
+	"github.com/yangirxd/goods-service/internal/metrics"
 	"github.com/yangirxd/goods-service/internal/models"
+	"github.com/yangirxd/goods-service/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// dbRole роль PostgreSQL, под которую переключается каждая транзакция с goods.
+// На неё действуют политики RLS из миграции 0006: строки goods и projects
+// видны ей только для проектов из настройки app.project_ids.
+const dbRole = "goods_service"
+
 type GoodsRepository struct {
 	db *sql.DB
 }
@@ -18,249 +32,392 @@ func NewGoodsRepository(db *sql.DB) *GoodsRepository {
 }
 
 func (r *GoodsRepository) Create(ctx context.Context, good *models.GoodCreate) (*models.Good, error) {
-	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
-	if err != nil {
-		return nil, fmt.Errorf("begin transaction: %w", err)
-	}
-	defer tx.Rollback()
-
-	var maxPriority int
-	err = tx.QueryRowContext(ctx, `
-		SELECT COALESCE(MAX(priority), 0) 
-		FROM goods 
-		WHERE project_id = $1
-	`, good.ProjectID).Scan(&maxPriority)
-	if err != nil {
-		return nil, fmt.Errorf("get max priority: %w", err)
-	}
+	ctx, done := track(ctx, "create")
+	defer done()
 
 	newGood := &models.Good{}
-	err = tx.QueryRowContext(ctx, `
-		INSERT INTO goods (project_id, name, description, priority) 
-		VALUES ($1, $2, $3, $4)
-		RETURNING id, project_id, name, description, priority, removed, created_at
-	`, good.ProjectID, good.Name, good.Description, maxPriority+1).
-		Scan(&newGood.ID, &newGood.ProjectID, &newGood.Name, &newGood.Description,
-			&newGood.Priority, &newGood.Removed, &newGood.CreatedAt)
-	if err != nil {
-		return nil, fmt.Errorf("insert good: %w", err)
-	}
+	err := r.inTx(ctx, sql.LevelReadCommitted, "create", auth.RoleEditor, func(tx *sql.Tx) error {
+		var maxPriority int
+		err := tx.QueryRowContext(ctx, `
+			SELECT COALESCE(MAX(priority), 0) 
+			FROM goods 
+			WHERE project_id = $1
+		`, good.ProjectID).Scan(&maxPriority)
+		if err != nil {
+			return fmt.Errorf("get max priority: %w", err)
+		}
 
-	if err = tx.Commit(); err != nil {
-		return nil, fmt.Errorf("commit transaction: %w", err)
+		err = tx.QueryRowContext(ctx, `
+			INSERT INTO goods (project_id, name, description, priority) 
+			VALUES ($1, $2, $3, $4)
+			RETURNING id, project_id, name, description, priority, removed, created_at
+		`, good.ProjectID, good.Name, good.Description, maxPriority+1).
+			Scan(&newGood.ID, &newGood.ProjectID, &newGood.Name, &newGood.Description,
+				&newGood.Priority, &newGood.Removed, &newGood.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("insert good: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return newGood, nil
 }
 
 func (r *GoodsRepository) Get(ctx context.Context, id int64) (*models.Good, error) {
-	good := &models.Good{}
-	err := r.db.QueryRowContext(ctx, `
-		SELECT id, project_id, name, description, priority, removed, created_at
-		FROM goods
-		WHERE id = $1 AND removed = false
-	`, id).Scan(&good.ID, &good.ProjectID, &good.Name, &good.Description,
-		&good.Priority, &good.Removed, &good.CreatedAt)
+	ctx, done := track(ctx, "get")
+	defer done()
+
+	var good *models.Good
+	err := r.inTx(ctx, sql.LevelReadCommitted, "get", auth.RoleViewer, func(tx *sql.Tx) error {
+		good = &models.Good{}
+		err := tx.QueryRowContext(ctx, `
+			SELECT id, project_id, name, description, priority, removed, created_at
+			FROM goods
+			WHERE id = $1 AND removed = false
+		`, id).Scan(&good.ID, &good.ProjectID, &good.Name, &good.Description,
+			&good.Priority, &good.Removed, &good.CreatedAt)
 
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, nil
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				good = nil
+				return nil
+			}
+			return fmt.Errorf("select good: %w", err)
 		}
-		return nil, fmt.Errorf("select good: %w", err)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return good, nil
 }
 
-func (r *GoodsRepository) Update(ctx context.Context, id int64, update *models.GoodUpdate) (*models.Good, error) {
-	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+// ProjectOf возвращает проект товара, в том числе удалённого. found равен
+// false, если товара нет или его проект не виден субъекту запроса.
+func (r *GoodsRepository) ProjectOf(ctx context.Context, id int64) (projectID int64, found bool, err error) {
+	ctx, done := track(ctx, "project_of")
+	defer done()
+
+	err = r.inTx(ctx, sql.LevelReadCommitted, "project_of", auth.RoleViewer, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, `
+			SELECT project_id FROM goods WHERE id = $1
+		`, id).Scan(&projectID)
+		if errors.Is(err, sql.ErrNoRows) {
+			found = false
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("select good project: %w", err)
+		}
+		found = true
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("begin transaction: %w", err)
+		return 0, false, err
 	}
-	defer tx.Rollback()
 
-	good := &models.Good{}
-	err = tx.QueryRowContext(ctx, `
-		SELECT id, project_id, name, description, priority, removed, created_at
-		FROM goods
-		WHERE id = $1 AND removed = false
-		FOR UPDATE
-	`, id).Scan(&good.ID, &good.ProjectID, &good.Name, &good.Description,
-		&good.Priority, &good.Removed, &good.CreatedAt)
+	return projectID, found, nil
+}
 
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, nil
+// GetMany возвращает неудалённые товары с указанными ID
+func (r *GoodsRepository) GetMany(ctx context.Context, ids []int64) ([]*models.Good, error) {
+	ctx, done := track(ctx, "get_many")
+	defer done()
+
+	var goods []*models.Good
+	err := r.inTx(ctx, sql.LevelReadCommitted, "get_many", auth.RoleViewer, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, `
+			SELECT id, project_id, name, description, priority, removed, created_at
+			FROM goods
+			WHERE id = ANY($1::bigint[]) AND removed = false
+		`, ids)
+		if err != nil {
+			return fmt.Errorf("select goods: %w", err)
 		}
-		return nil, fmt.Errorf("select good for update: %w", err)
+		goods, err = scanGoods(rows)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	if update.Name != nil {
-		good.Name = *update.Name
-	}
-	if update.Description != nil {
-		good.Description = *update.Description
-	}
+	return goods, nil
+}
 
-	err = tx.QueryRowContext(ctx, `
-		UPDATE goods
-		SET name = $1, description = $2
-		WHERE id = $3
-		RETURNING id, project_id, name, description, priority, removed, created_at
-	`, good.Name, good.Description, id).
-		Scan(&good.ID, &good.ProjectID, &good.Name, &good.Description,
+func (r *GoodsRepository) Update(ctx context.Context, id int64, update *models.GoodUpdate) (*models.Good, error) {
+	ctx, done := track(ctx, "update")
+	defer done()
+
+	var good *models.Good
+	err := r.inTx(ctx, sql.LevelSerializable, "update", auth.RoleEditor, func(tx *sql.Tx) error {
+		good = &models.Good{}
+		err := tx.QueryRowContext(ctx, `
+			SELECT id, project_id, name, description, priority, removed, created_at
+			FROM goods
+			WHERE id = $1 AND removed = false
+			FOR UPDATE
+		`, id).Scan(&good.ID, &good.ProjectID, &good.Name, &good.Description,
 			&good.Priority, &good.Removed, &good.CreatedAt)
 
-	if err != nil {
-		return nil, fmt.Errorf("update good: %w", err)
-	}
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				good = nil
+				return nil
+			}
+			return fmt.Errorf("select good for update: %w", err)
+		}
 
-	if err = tx.Commit(); err != nil {
-		return nil, fmt.Errorf("commit transaction: %w", err)
+		if update.Name != nil {
+			good.Name = *update.Name
+		}
+		if update.Description != nil {
+			good.Description = *update.Description
+		}
+
+		err = tx.QueryRowContext(ctx, `
+			UPDATE goods
+			SET name = $1, description = $2
+			WHERE id = $3
+			RETURNING id, project_id, name, description, priority, removed, created_at
+		`, good.Name, good.Description, id).
+			Scan(&good.ID, &good.ProjectID, &good.Name, &good.Description,
+				&good.Priority, &good.Removed, &good.CreatedAt)
+
+		if err != nil {
+			return fmt.Errorf("update good: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return good, nil
 }
 
 func (r *GoodsRepository) Delete(ctx context.Context, id int64) error {
-	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
-	if err != nil {
-		return fmt.Errorf("begin transaction: %w", err)
-	}
-	defer tx.Rollback()
+	ctx, done := track(ctx, "delete")
+	defer done()
+
+	return r.inTx(ctx, sql.LevelSerializable, "delete", auth.RoleEditor, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			UPDATE goods
+			SET removed = true
+			WHERE id = $1 AND removed = false
+		`, id)
+		if err != nil {
+			return fmt.Errorf("delete good: %w", err)
+		}
+		return nil
+	})
+}
 
-	result, err := tx.ExecContext(ctx, `
-		UPDATE goods
-		SET removed = true
-		WHERE id = $1 AND removed = false
-	`, id)
-	if err != nil {
-		return fmt.Errorf("delete good: %w", err)
-	}
+// List возвращает страницу товаров из указанных проектов. nil вместо
+// списка проектов означает все проекты.
+func (r *GoodsRepository) List(ctx context.Context, projectIDs []int64, limit, offset int) ([]*models.Good, int, int, error) { // Получаем общее количество записей и количество удалённых
+	ctx, done := track(ctx, "list")
+	defer done()
+
+	var (
+		goods          []*models.Good
+		total, removed int
+	)
+	err := r.inTx(ctx, sql.LevelReadCommitted, "list", auth.RoleViewer, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, `
+			SELECT 
+				COUNT(*) as total,
+				COUNT(*) FILTER (WHERE removed = true) as removed
+			FROM goods
+			WHERE $1::bigint[] IS NULL OR project_id = ANY($1::bigint[])
+		`, projectIDs).Scan(&total, &removed)
+		if err != nil {
+			return fmt.Errorf("count goods: %w", err)
+		}
 
-	affected, err := result.RowsAffected()
+		rows, err := tx.QueryContext(ctx, `
+			SELECT id, project_id, name, description, priority, removed, created_at
+			FROM goods
+			WHERE removed = false
+			AND ($3::bigint[] IS NULL OR project_id = ANY($3::bigint[]))
+			ORDER BY priority
+			LIMIT $1 OFFSET $2
+		`, limit, offset, projectIDs)
+		if err != nil {
+			return fmt.Errorf("select goods: %w", err)
+		}
+		goods, err = scanGoods(rows)
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("get rows affected: %w", err)
-	}
-	if affected == 0 {
-		return nil
-	}
-
-	if err = tx.Commit(); err != nil {
-		return fmt.Errorf("commit transaction: %w", err)
+		return nil, 0, 0, err
 	}
 
-	return nil
+	return goods, total, removed, nil
 }
 
-func (r *GoodsRepository) List(ctx context.Context, limit, offset int) ([]*models.Good, int, int, error) { // Получаем общее количество записей и количество удалённых
-	var total, removed int
-	err := r.db.QueryRowContext(ctx, `
-		SELECT 
-			COUNT(*) as total,
-			COUNT(*) FILTER (WHERE removed = true) as removed
-		FROM goods
-	`).Scan(&total, &removed)
-	if err != nil {
-		return nil, 0, 0, fmt.Errorf("count goods: %w", err)
-	}
-
-	rows, err := r.db.QueryContext(ctx, `
-		SELECT id, project_id, name, description, priority, removed, created_at
-		FROM goods
-		WHERE removed = false
-		ORDER BY priority
-		LIMIT $1 OFFSET $2
-	`, limit, offset)
-	if err != nil {
-		return nil, 0, 0, fmt.Errorf("select goods: %w", err)
-	}
-	defer rows.Close()
+// TopByPriority возвращает до n товаров с наивысшим приоритетом в каждом проекте
+func (r *GoodsRepository) TopByPriority(ctx context.Context, n int) ([]*models.Good, error) {
+	ctx, done := track(ctx, "top_by_priority")
+	defer done()
 
 	var goods []*models.Good
-	for rows.Next() {
-		good := &models.Good{}
-		err := rows.Scan(&good.ID, &good.ProjectID, &good.Name, &good.Description,
-			&good.Priority, &good.Removed, &good.CreatedAt)
+	err := r.inTx(ctx, sql.LevelReadCommitted, "top_by_priority", auth.RoleViewer, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, `
+			SELECT id, project_id, name, description, priority, removed, created_at
+			FROM (
+				SELECT *, ROW_NUMBER() OVER (PARTITION BY project_id ORDER BY priority) AS rn
+				FROM goods
+				WHERE removed = false
+			) ranked
+			WHERE rn <= $1
+		`, n)
 		if err != nil {
-			return nil, 0, 0, fmt.Errorf("scan good: %w", err)
+			return fmt.Errorf("select top goods: %w", err)
 		}
-		goods = append(goods, good)
-	}
-
-	if err = rows.Err(); err != nil {
-		return nil, 0, 0, fmt.Errorf("iterate goods: %w", err)
+		goods, err = scanGoods(rows)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return goods, total, removed, nil
+	return goods, nil
 }
 
 func (r *GoodsRepository) Reprioritize(ctx context.Context, id int64, projectID int64, newPriority int) ([]*models.Good, error) {
-	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	ctx, done := track(ctx, "reprioritize")
+	defer done()
+
+	var updatedGoods []*models.Good
+	err := r.inTx(ctx, sql.LevelSerializable, "reprioritize", auth.RoleEditor, func(tx *sql.Tx) error {
+		updatedGoods = nil
+
+		var currentPriority int
+		err := tx.QueryRowContext(ctx, `
+			SELECT priority 
+			FROM goods 
+			WHERE id = $1 AND project_id = $2 AND removed = false
+			FOR UPDATE
+		`, id, projectID).Scan(&currentPriority)
+
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil
+			}
+			return fmt.Errorf("get current priority: %w", err)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE goods
+			SET priority = $3
+			WHERE id = $1
+			AND project_id = $2
+			AND removed = false
+		`, id, projectID, newPriority)
+		if err != nil {
+			return fmt.Errorf("update priority: %w", err)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			WITH ordered_goods AS (
+				SELECT id, ROW_NUMBER() OVER (ORDER BY priority) as rn
+				FROM goods
+				WHERE project_id = $1
+				AND removed = false
+				AND priority >= $4
+				AND id != $3
+			)
+			UPDATE goods g
+			SET priority = $2 + og.rn
+			FROM ordered_goods og
+			WHERE g.id = og.id
+		`, projectID, newPriority, id, currentPriority)
+
+		if err != nil {
+			return fmt.Errorf("update priorities: %w", err)
+		}
+
+		// Получаем список всех обновлённых товаров
+		rows, err := tx.QueryContext(ctx, `
+			SELECT id, project_id, name, description, priority, removed, created_at
+			FROM goods
+			WHERE project_id = $1 AND removed = false
+			AND priority >= $2
+			ORDER BY priority
+		`, projectID, newPriority)
+		if err != nil {
+			return fmt.Errorf("select updated goods: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			good := &models.Good{}
+			err := rows.Scan(&good.ID, &good.ProjectID, &good.Name, &good.Description,
+				&good.Priority, &good.Removed, &good.CreatedAt)
+			if err != nil {
+				return fmt.Errorf("scan good: %w", err)
+			}
+			updatedGoods = append(updatedGoods, good)
+		}
+
+		if err = rows.Err(); err != nil {
+			return fmt.Errorf("iterate goods: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("begin transaction: %w", err)
+		return nil, err
 	}
-	defer tx.Rollback()
 
-	var currentPriority int
-	err = tx.QueryRowContext(ctx, `
-		SELECT priority 
-		FROM goods 
-		WHERE id = $1 AND project_id = $2 AND removed = false
-		FOR UPDATE
-	`, id, projectID).Scan(&currentPriority)
+	return updatedGoods, nil
+}
 
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, nil
-		}
-		return nil, fmt.Errorf("get current priority: %w", err)
+// inTx выполняет fn в транзакции. Транзакции видны только проекты, в
+// которых у субъекта запроса есть роль не ниже role. Транзакции, прерванные
+// конфликтом сериализации или взаимной блокировкой, не повторяются, а
+// учитываются в метрике и возвращают ошибку.
+func (r *GoodsRepository) inTx(ctx context.Context, isolation sql.IsolationLevel, operation, role string, fn func(tx *sql.Tx) error) error {
+	err := r.runTx(ctx, isolation, role, fn)
+	if conflict(err) {
+		metrics.TxConflicts.WithLabelValues(operation).Inc()
+		trace.SpanFromContext(ctx).AddEvent("transaction conflict", trace.WithAttributes(
+			attribute.String("error", err.Error()),
+		))
 	}
+	return err
+}
 
-	_, err = tx.ExecContext(ctx, `
-		UPDATE goods
-		SET priority = $3
-		WHERE id = $1
-		AND project_id = $2
-		AND removed = false
-	`, id, projectID, newPriority)
+func (r *GoodsRepository) runTx(ctx context.Context, isolation sql.IsolationLevel, role string, fn func(tx *sql.Tx) error) error {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: isolation})
 	if err != nil {
-		return nil, fmt.Errorf("update priority: %w", err)
+		return fmt.Errorf("begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	_, err = tx.ExecContext(ctx, `
-		WITH ordered_goods AS (
-			SELECT id, ROW_NUMBER() OVER (ORDER BY priority) as rn
-			FROM goods
-			WHERE project_id = $1
-			AND removed = false
-			AND priority >= $4
-			AND id != $3
-		)
-		UPDATE goods g
-		SET priority = $2 + og.rn
-		FROM ordered_goods og
-		WHERE g.id = og.id
-	`, projectID, newPriority, id, currentPriority)
+	if err := scope(ctx, tx, role); err != nil {
+		return err
+	}
 
-	if err != nil {
-		return nil, fmt.Errorf("update priorities: %w", err)
+	if err := fn(tx); err != nil {
+		return err
 	}
 
-	// Получаем список всех обновлённых товаров
-	rows, err := tx.QueryContext(ctx, `
-		SELECT id, project_id, name, description, priority, removed, created_at
-		FROM goods
-		WHERE project_id = $1 AND removed = false
-		AND priority >= $2
-		ORDER BY priority
-	`, projectID, newPriority)
-	if err != nil {
-		return nil, fmt.Errorf("select updated goods: %w", err)
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
 	}
+
+	return nil
+}
+
+// scanGoods читает товары из rows и закрывает их
+func scanGoods(rows *sql.Rows) ([]*models.Good, error) {
 	defer rows.Close()
 
-	var updatedGoods []*models.Good
+	var goods []*models.Good
 	for rows.Next() {
 		good := &models.Good{}
 		err := rows.Scan(&good.ID, &good.ProjectID, &good.Name, &good.Description,
@@ -268,16 +425,76 @@ func (r *GoodsRepository) Reprioritize(ctx context.Context, id int64, projectID
 		if err != nil {
 			return nil, fmt.Errorf("scan good: %w", err)
 		}
-		updatedGoods = append(updatedGoods, good)
+		goods = append(goods, good)
 	}
 
-	if err = rows.Err(); err != nil {
+	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("iterate goods: %w", err)
 	}
+	return goods, nil
+}
 
-	if err = tx.Commit(); err != nil {
-		return nil, fmt.Errorf("commit transaction: %w", err)
+// scope переключает транзакцию на роль dbRole и записывает в app.project_ids
+// проекты, в которых у субъекта запроса есть роль не ниже role. Обе настройки
+// действуют до конца транзакции.
+func scope(ctx context.Context, tx *sql.Tx, role string) error {
+	_, err := tx.ExecContext(ctx, `
+		SELECT set_config('app.project_ids', $1, true), set_config('role', $2, true)
+	`, projectScope(ctx, role), dbRole)
+	if err != nil {
+		return fmt.Errorf("set project scope: %w", err)
 	}
+	return nil
+}
 
-	return updatedGoods, nil
+// projectScope возвращает значение app.project_ids: ID проектов через
+// запятую или * для всех проектов. Без прав в контексте все проекты доступны
+// только запросам без субъекта, то есть фоновым задачам и API без
+// аутентификации; субъекту, чьи права не проверены, не доступен ни один.
+func projectScope(ctx context.Context, role string) string {
+	access, ok := auth.AccessFromContext(ctx)
+	if !ok {
+		if _, ok := auth.FromContext(ctx); ok {
+			return ""
+		}
+		return "*"
+	}
+	if access.All() {
+		return "*"
+	}
+
+	ids := access.Projects(role)
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.FormatInt(id, 10)
+	}
+	return strings.Join(parts, ",")
+}
+
+// conflict сообщает, что транзакция прервана конфликтом с другой транзакцией
+func conflict(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	// serialization_failure и deadlock_detected
+	return pgErr.Code == "40001" || pgErr.Code == "40P01"
+}
+
+// track начинает span операции и возвращает функцию, которая завершает его
+// и записывает время выполнения
+func track(ctx context.Context, operation string) (context.Context, func()) {
+	start := time.Now()
+	ctx, span := tracing.Tracer.Start(ctx, "repository."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation.name", operation),
+		),
+	)
+
+	return ctx, func() {
+		metrics.QueryDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+		span.End()
+	}
 }
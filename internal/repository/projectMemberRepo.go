@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/yangirxd/goods-service/internal/models"
+)
+
+// ErrProjectNotFound возвращается при назначении роли в несуществующем проекте
+var ErrProjectNotFound = errors.New("project not found")
+
+type ProjectMemberRepository struct {
+	db *sql.DB
+}
+
+func NewProjectMemberRepository(db *sql.DB) *ProjectMemberRepository {
+	return &ProjectMemberRepository{db: db}
+}
+
+// Roles возвращает роли субъекта по ID проекта
+func (r *ProjectMemberRepository) Roles(ctx context.Context, subject string) (map[int64]string, error) {
+	ctx, done := track(ctx, "project_member_roles")
+	defer done()
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT project_id, role
+		FROM project_members
+		WHERE subject = $1
+	`, subject)
+	if err != nil {
+		return nil, fmt.Errorf("select project roles: %w", err)
+	}
+	defer rows.Close()
+
+	roles := make(map[int64]string)
+	for rows.Next() {
+		var (
+			projectID int64
+			role      string
+		)
+		if err := rows.Scan(&projectID, &role); err != nil {
+			return nil, fmt.Errorf("scan project role: %w", err)
+		}
+		roles[projectID] = role
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate project roles: %w", err)
+	}
+	return roles, nil
+}
+
+// List возвращает участников проекта по subject
+func (r *ProjectMemberRepository) List(ctx context.Context, projectID int64) ([]*models.ProjectMember, error) {
+	ctx, done := track(ctx, "project_member_list")
+	defer done()
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT project_id, subject, role, created_by, created_at
+		FROM project_members
+		WHERE project_id = $1
+		ORDER BY subject
+	`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("select project members: %w", err)
+	}
+	defer rows.Close()
+
+	var members []*models.ProjectMember
+	for rows.Next() {
+		member := &models.ProjectMember{}
+		err := rows.Scan(&member.ProjectID, &member.Subject, &member.Role, &member.CreatedBy, &member.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("scan project member: %w", err)
+		}
+		members = append(members, member)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate project members: %w", err)
+	}
+	return members, nil
+}
+
+// Put назначает субъекту роль в проекте или меняет уже назначенную.
+// Для несуществующего проекта возвращает ErrProjectNotFound.
+func (r *ProjectMemberRepository) Put(ctx context.Context, member *models.ProjectMember) (*models.ProjectMember, error) {
+	ctx, done := track(ctx, "project_member_put")
+	defer done()
+
+	saved := &models.ProjectMember{}
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO project_members (project_id, subject, role, created_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (project_id, subject) DO UPDATE SET role = EXCLUDED.role
+		RETURNING project_id, subject, role, created_by, created_at
+	`, member.ProjectID, member.Subject, member.Role, member.CreatedBy).
+		Scan(&saved.ProjectID, &saved.Subject, &saved.Role, &saved.CreatedBy, &saved.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		// foreign_key_violation
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return nil, ErrProjectNotFound
+		}
+		return nil, fmt.Errorf("upsert project member: %w", err)
+	}
+	return saved, nil
+}
+
+// Delete отнимает у субъекта роль в проекте и сообщает, была ли она
+func (r *ProjectMemberRepository) Delete(ctx context.Context, projectID int64, subject string) (bool, error) {
+	ctx, done := track(ctx, "project_member_delete")
+	defer done()
+
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM project_members
+		WHERE project_id = $1 AND subject = $2
+	`, projectID, subject)
+	if err != nil {
+		return false, fmt.Errorf("delete project member: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("delete project member: %w", err)
+	}
+	return affected > 0, nil
+}
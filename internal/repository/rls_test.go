@@ -0,0 +1,265 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/testcontainers/testcontainers-go"
+	tcpostgres "github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/yangirxd/goods-service/internal/auth"
+	"github.com/yangirxd/goods-service/internal/db"
+	"github.com/yangirxd/goods-service/internal/models"
+)
+
+// memberships роли одного субъекта по ID проекта
+type memberships map[int64]string
+
+func (m memberships) Roles(context.Context, string) (map[int64]string, error) {
+	return m, nil
+}
+
+// rlsFixture база с применёнными миграциями и двумя проектами по товару в каждом
+type rlsFixture struct {
+	// owner подключение владельца таблиц, на которого политики не действуют
+	owner *sql.DB
+	// app подключение сервиса под тем же пользователем, что и в продакшене
+	app *sql.DB
+
+	ownProject, otherProject int64
+	ownGood, otherGood       int64
+}
+
+// newRLSFixture запускает PostgreSQL в контейнере, создаёт пользователя
+// сервиса без прав суперпользователя и выполняет под ним миграции
+func newRLSFixture(t *testing.T) *rlsFixture {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("starts a PostgreSQL container")
+	}
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+
+	ctx := context.Background()
+	container, err := tcpostgres.Run(ctx, "postgres:16-alpine",
+		tcpostgres.WithDatabase("goods"),
+		tcpostgres.WithUsername("postgres"),
+		tcpostgres.WithPassword("postgres"),
+		tcpostgres.BasicWaitStrategies(),
+	)
+	testcontainers.CleanupContainer(t, container)
+	if err != nil {
+		t.Fatalf("start postgres: %v", err)
+	}
+
+	superDSN, err := container.ConnectionString(ctx, "sslmode=disable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	super := openDB(t, superDSN)
+	for _, stmt := range []string{
+		`CREATE ROLE app LOGIN PASSWORD 'app' CREATEROLE`,
+		`ALTER DATABASE goods OWNER TO app`,
+	} {
+		if _, err := super.ExecContext(ctx, stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+
+	host, err := container.Host(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := container.MappedPort(ctx, "5432/tcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := openDB(t, fmt.Sprintf("postgres://app:app@%s:%s/goods?sslmode=disable", host, port.Port()))
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	if err := db.RunMigrations(app, "../../migrations/postgres", logger); err != nil {
+		t.Fatalf("run migrations: %v", err)
+	}
+
+	// Миграции выполнил app, поэтому он владелец таблиц и видит все строки
+	f := &rlsFixture{owner: app, app: app}
+	f.ownProject = insertID(t, app, `INSERT INTO projects (name) VALUES ('own') RETURNING id`)
+	f.otherProject = insertID(t, app, `INSERT INTO projects (name) VALUES ('other') RETURNING id`)
+	f.ownGood = insertID(t, app, `INSERT INTO goods (project_id, name, description, priority) VALUES ($1, 'own', '', 1) RETURNING id`, f.ownProject)
+	f.otherGood = insertID(t, app, `INSERT INTO goods (project_id, name, description, priority) VALUES ($1, 'other', '', 1) RETURNING id`, f.otherProject)
+	return f
+}
+
+func openDB(t *testing.T, dsn string) *sql.DB {
+	t.Helper()
+
+	conn, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	if err := conn.Ping(); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	return conn
+}
+
+func insertID(t *testing.T, conn *sql.DB, query string, args ...interface{}) int64 {
+	t.Helper()
+
+	var id int64
+	if err := conn.QueryRow(query, args...).Scan(&id); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	return id
+}
+
+// editorContext возвращает контекст субъекта с ролью editor только в проекте projectID
+func editorContext(t *testing.T, projectID int64) context.Context {
+	t.Helper()
+
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "alice", Method: "test"})
+	access, err := auth.NewProjects(memberships{projectID: auth.RoleEditor}).Access(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return auth.WithAccess(ctx, access)
+}
+
+func TestRowLevelSecurityHidesOtherProjects(t *testing.T) {
+	f := newRLSFixture(t)
+	repo := NewGoodsRepository(f.app)
+	ctx := editorContext(t, f.ownProject)
+
+	t.Run("select", func(t *testing.T) {
+		good, err := repo.Get(ctx, f.otherGood)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if good != nil {
+			t.Errorf("Get() returned a good of another project: %+v", good)
+		}
+
+		// Запрос без условия на проект всё равно видит только свой проект
+		goods, total, _, err := repo.List(ctx, nil, 100, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total != 1 || len(goods) != 1 || goods[0].ID != f.ownGood {
+			t.Errorf("List() = %d goods of %d total, want only good %d", len(goods), total, f.ownGood)
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		name := "hijacked"
+		good, err := repo.Update(ctx, f.otherGood, &models.GoodUpdate{Name: &name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if good != nil {
+			t.Errorf("Update() changed a good of another project: %+v", good)
+		}
+		assertOtherGoodIntact(t, f)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if err := repo.Delete(ctx, f.otherGood); err != nil {
+			t.Fatal(err)
+		}
+		assertOtherGoodIntact(t, f)
+	})
+
+	t.Run("raw statements", func(t *testing.T) {
+		tx, err := f.app.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tx.Rollback()
+		if err := scope(ctx, tx, auth.RoleEditor); err != nil {
+			t.Fatal(err)
+		}
+
+		var count int
+		if err := tx.QueryRowContext(ctx, `SELECT count(*) FROM goods WHERE project_id = $1`, f.otherProject).Scan(&count); err != nil {
+			t.Fatal(err)
+		}
+		if count != 0 {
+			t.Errorf("SELECT saw %d goods of another project", count)
+		}
+
+		result, err := tx.ExecContext(ctx, `UPDATE goods SET name = 'hijacked' WHERE project_id = $1`, f.otherProject)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n, _ := result.RowsAffected(); n != 0 {
+			t.Errorf("UPDATE changed %d goods of another project", n)
+		}
+
+		// Сервис удаляет товары флагом removed, DELETE роли не выдан вовсе
+		if _, err := tx.ExecContext(ctx, `DELETE FROM goods WHERE project_id = $1`, f.otherProject); err == nil {
+			t.Error("DELETE is allowed for goods_service")
+		}
+	})
+}
+
+func TestRowLevelSecurityFailsClosedWithoutProjects(t *testing.T) {
+	f := newRLSFixture(t)
+	ctx := context.Background()
+
+	t.Run("raw statements", func(t *testing.T) {
+		tx, err := f.app.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tx.Rollback()
+
+		// Роль переключена, а app.project_ids не задана
+		if _, err := tx.ExecContext(ctx, `SELECT set_config('role', $1, true)`, dbRole); err != nil {
+			t.Fatal(err)
+		}
+
+		var count int
+		if err := tx.QueryRowContext(ctx, `SELECT count(*) FROM goods`).Scan(&count); err != nil {
+			t.Fatal(err)
+		}
+		if count != 0 {
+			t.Errorf("SELECT saw %d goods without app.project_ids", count)
+		}
+
+		if _, err := tx.ExecContext(ctx, `INSERT INTO goods (project_id, name, priority) VALUES ($1, 'new', 2)`, f.ownProject); err == nil {
+			t.Error("INSERT succeeded without app.project_ids")
+		}
+	})
+
+	t.Run("repository", func(t *testing.T) {
+		// Субъект без прав в контексте не видит ни одного проекта
+		ctx := auth.WithPrincipal(ctx, &auth.Principal{Subject: "alice", Method: "test"})
+		good, err := NewGoodsRepository(f.app).Get(ctx, f.ownGood)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if good != nil {
+			t.Errorf("Get() returned a good without project access: %+v", good)
+		}
+	})
+}
+
+// assertOtherGoodIntact проверяет от имени владельца, что товар чужого проекта не изменился
+func assertOtherGoodIntact(t *testing.T, f *rlsFixture) {
+	t.Helper()
+
+	var (
+		name    string
+		removed bool
+	)
+	err := f.owner.QueryRow(`SELECT name, removed FROM goods WHERE id = $1`, f.otherGood).Scan(&name, &removed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "other" || removed {
+		t.Errorf("good of another project changed: name %q, removed %v", name, removed)
+	}
+}
@@ -0,0 +1,114 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+var (
+	recorder     = tracetest.NewSpanRecorder()
+	recorderOnce sync.Once
+)
+
+// recordSpans направляет span'ы Tracer в recorder. Глобальный провайдер
+// можно задать только один раз, поэтому тесты делят recorder и
+// ищут свои span'ы по имени.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	recorderOnce.Do(func() {
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+		otel.SetTextMapPropagator(propagation.TraceContext{})
+	})
+	return recorder
+}
+
+func findSpan(t *testing.T, r *tracetest.SpanRecorder, name string) sdktrace.ReadOnlySpan {
+	t.Helper()
+
+	spans := r.Ended()
+	for i := len(spans) - 1; i >= 0; i-- {
+		if spans[i].Name() == name {
+			return spans[i]
+		}
+	}
+	t.Fatalf("no span %q among %d ended spans", name, len(spans))
+	return nil
+}
+
+func TestMiddlewareContinuesTrace(t *testing.T) {
+	r := recordSpans(t)
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/goods/get/:id", func(c *gin.Context) {
+		c.Status(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/goods/get/1", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	span := findSpan(t, r, "GET /goods/get/:id")
+	if got := span.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("TraceID = %s, want the incoming trace", got)
+	}
+	if got := span.Parent().SpanID().String(); got != "00f067aa0ba902b7" {
+		t.Errorf("parent SpanID = %s, want the incoming span", got)
+	}
+	if span.Status().Code != codes.Error {
+		t.Errorf("Status = %v, want Error for a 500", span.Status())
+	}
+}
+
+func TestRedisHook(t *testing.T) {
+	r := recordSpans(t)
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	client.AddHook(RedisHook{})
+	ctx := context.Background()
+
+	// Отсутствие ключа ошибкой не считается
+	if err := client.Get(ctx, "missing").Err(); err != redis.Nil {
+		t.Fatalf("Get() error = %v, want redis.Nil", err)
+	}
+	if span := findSpan(t, r, "redis get"); span.Status().Code == codes.Error {
+		t.Errorf("Status = %v for a missing key, want no error", span.Status())
+	}
+
+	server.SetError("LOADING Redis is loading the dataset in memory")
+	client.Incr(ctx, "counter")
+	if span := findSpan(t, r, "redis incr"); span.Status().Code != codes.Error {
+		t.Errorf("Status = %v for a failed command, want Error", span.Status())
+	}
+
+	server.SetError("")
+	_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, "a", 1, 0)
+		pipe.Set(ctx, "b", 2, 0)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Pipelined() error = %v", err)
+	}
+	span := findSpan(t, r, "redis pipeline")
+	for _, attr := range span.Attributes() {
+		if attr.Key == "db.operation.batch.size" && attr.Value.AsInt64() != 2 {
+			t.Errorf("batch size = %d, want 2", attr.Value.AsInt64())
+		}
+	}
+}
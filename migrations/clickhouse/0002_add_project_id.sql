@@ -0,0 +1,3 @@
+-- Для старых записей project_id вычисляется из данных события
+ALTER TABLE logs.goods_events
+    ADD COLUMN IF NOT EXISTS project_id Int64 DEFAULT JSONExtractInt(data, 'project_id') AFTER entity_id;
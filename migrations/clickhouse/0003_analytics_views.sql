@@ -0,0 +1,69 @@
+-- Агрегаты заполняются по уже записанным событиям при создании таблиц, а
+-- новые события добавляют материализованные представления. Если таблица уже
+-- существует, она не пересчитывается, поэтому миграцию можно повторять.
+-- Перед применением остановите потребителя логов: события, записанные между
+-- созданием таблицы и её представления, в статистику не попадут.
+
+-- Количество событий каждого типа по проектам и дням
+CREATE TABLE IF NOT EXISTS logs.goods_daily_actions
+(
+    event_date Date,
+    project_id Int64,
+    action String,
+    events UInt64
+)
+ENGINE = SummingMergeTree(events)
+PARTITION BY toYYYYMM(event_date)
+ORDER BY (project_id, event_date, action)
+AS
+SELECT
+    event_date,
+    project_id,
+    action,
+    count() AS events
+FROM logs.goods_events
+GROUP BY event_date, project_id, action;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS logs.goods_daily_actions_mv
+TO logs.goods_daily_actions
+AS
+SELECT
+    event_date,
+    project_id,
+    action,
+    count() AS events
+FROM logs.goods_events
+GROUP BY event_date, project_id, action;
+
+-- Количество изменений приоритета каждого товара по дням
+CREATE TABLE IF NOT EXISTS logs.goods_reprioritizations
+(
+    event_date Date,
+    project_id Int64,
+    entity_id Int64,
+    reprioritizations UInt64
+)
+ENGINE = SummingMergeTree(reprioritizations)
+PARTITION BY toYYYYMM(event_date)
+ORDER BY (project_id, event_date, entity_id)
+AS
+SELECT
+    event_date,
+    project_id,
+    entity_id,
+    count() AS reprioritizations
+FROM logs.goods_events
+WHERE action = 'reprioritize'
+GROUP BY event_date, project_id, entity_id;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS logs.goods_reprioritizations_mv
+TO logs.goods_reprioritizations
+AS
+SELECT
+    event_date,
+    project_id,
+    entity_id,
+    count() AS reprioritizations
+FROM logs.goods_events
+WHERE action = 'reprioritize'
+GROUP BY event_date, project_id, entity_id;
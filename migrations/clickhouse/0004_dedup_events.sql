@@ -0,0 +1,108 @@
+-- Переводим журнал событий на ReplacingMergeTree с ключом event_id,
+-- чтобы повторно записанные события схлопывались при слияниях, а агрегаты
+-- аналитики — на состояния uniqExact по event_id, чтобы копии одного события,
+-- ещё не схлопнутые слиянием, считались один раз.
+-- Перед применением остановите потребителя логов:
+-- события, записанные во время копирования, в новую таблицу не попадут.
+-- Миграцию можно выполнять повторно, в том числе после сбоя: таблица
+-- goods_events_dedup служит только для копирования и пересоздаётся, а после
+-- перевода повторный запуск копирует таблицу вместе с её event_id.
+
+DROP VIEW IF EXISTS logs.goods_daily_actions_mv;
+DROP VIEW IF EXISTS logs.goods_reprioritizations_mv;
+
+-- Старым событиям идентификатор назначается при копировании
+ALTER TABLE logs.goods_events
+    ADD COLUMN IF NOT EXISTS event_id UUID DEFAULT generateUUIDv4() FIRST;
+
+-- Остатки прерванного запуска: либо неполная копия, либо старая таблица после обмена
+DROP TABLE IF EXISTS logs.goods_events_dedup;
+
+CREATE TABLE IF NOT EXISTS logs.goods_events_dedup AS logs.goods_events
+ENGINE = ReplacingMergeTree()
+PARTITION BY toYYYYMM(event_date)
+ORDER BY (project_id, entity_id, timestamp, event_id);
+
+INSERT INTO logs.goods_events_dedup
+SELECT * FROM logs.goods_events;
+
+-- Обмен выполняется, только если копия действительно ReplacingMergeTree
+SELECT throwIf(
+    (SELECT engine FROM system.tables WHERE database = 'logs' AND name = 'goods_events_dedup') != 'ReplacingMergeTree',
+    'logs.goods_events_dedup is not a ReplacingMergeTree table'
+);
+
+EXCHANGE TABLES logs.goods_events AND logs.goods_events_dedup;
+
+-- После обмена под этим именем лежит старая таблица
+DROP TABLE IF EXISTS logs.goods_events_dedup;
+
+-- Агрегаты из 0003 пересчитываются по журналу целиком
+DROP TABLE IF EXISTS logs.goods_daily_actions;
+DROP TABLE IF EXISTS logs.goods_reprioritizations;
+
+-- События каждого типа по проектам и дням
+CREATE TABLE IF NOT EXISTS logs.goods_daily_actions
+(
+    event_date Date,
+    project_id Int64,
+    action String,
+    events AggregateFunction(uniqExact, UUID)
+)
+ENGINE = AggregatingMergeTree()
+PARTITION BY toYYYYMM(event_date)
+ORDER BY (project_id, event_date, action);
+
+INSERT INTO logs.goods_daily_actions (event_date, project_id, action, events)
+SELECT
+    event_date,
+    project_id,
+    action,
+    uniqExactState(event_id) AS events
+FROM logs.goods_events
+GROUP BY event_date, project_id, action;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS logs.goods_daily_actions_mv
+TO logs.goods_daily_actions
+AS
+SELECT
+    event_date,
+    project_id,
+    action,
+    uniqExactState(event_id) AS events
+FROM logs.goods_events
+GROUP BY event_date, project_id, action;
+
+-- Изменения приоритета каждого товара по дням
+CREATE TABLE IF NOT EXISTS logs.goods_reprioritizations
+(
+    event_date Date,
+    project_id Int64,
+    entity_id Int64,
+    reprioritizations AggregateFunction(uniqExact, UUID)
+)
+ENGINE = AggregatingMergeTree()
+PARTITION BY toYYYYMM(event_date)
+ORDER BY (project_id, event_date, entity_id);
+
+INSERT INTO logs.goods_reprioritizations (event_date, project_id, entity_id, reprioritizations)
+SELECT
+    event_date,
+    project_id,
+    entity_id,
+    uniqExactState(event_id) AS reprioritizations
+FROM logs.goods_events
+WHERE action = 'reprioritize'
+GROUP BY event_date, project_id, entity_id;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS logs.goods_reprioritizations_mv
+TO logs.goods_reprioritizations
+AS
+SELECT
+    event_date,
+    project_id,
+    entity_id,
+    uniqExactState(event_id) AS reprioritizations
+FROM logs.goods_events
+WHERE action = 'reprioritize'
+GROUP BY event_date, project_id, entity_id;
@@ -0,0 +1,3 @@
+-- ID HTTP-запроса, который вызвал изменение; у старых записей пустой
+ALTER TABLE logs.goods_events
+    ADD COLUMN IF NOT EXISTS request_id String DEFAULT '' AFTER project_id;
@@ -0,0 +1,3 @@
+-- Субъект, выполнивший изменение; у старых записей и анонимных запросов пустой
+ALTER TABLE logs.goods_events
+    ADD COLUMN IF NOT EXISTS actor String DEFAULT '' AFTER request_id;
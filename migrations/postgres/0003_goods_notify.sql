@@ -0,0 +1,64 @@
+-- Уведомляет слушателей об изменении строк goods, кто бы их ни менял.
+-- Слушатель в сервисе удаляет изменённые товары из кэша.
+-- Триггеры срабатывают один раз на запрос и отправляют одно уведомление на
+-- каждый затронутый проект, поэтому изменение приоритета N товаров не
+-- порождает N уведомлений. ID разбиваются на пачки по 500, чтобы уведомление
+-- не превысило предел pg_notify в 8000 байт.
+CREATE OR REPLACE FUNCTION notify_goods_changed() RETURNS trigger AS $$
+DECLARE
+    changed_ids BIGINT[];
+    changed_projects BIGINT[];
+    change RECORD;
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        SELECT array_agg(id), array_agg(project_id) INTO changed_ids, changed_projects
+        FROM new_rows;
+    ELSIF TG_OP = 'DELETE' THEN
+        SELECT array_agg(id), array_agg(project_id) INTO changed_ids, changed_projects
+        FROM old_rows;
+    ELSE
+        -- Товар, перенесённый в другой проект, попадает в уведомления обоих проектов
+        SELECT array_agg(id), array_agg(project_id) INTO changed_ids, changed_projects
+        FROM (
+            SELECT id, project_id FROM old_rows
+            UNION
+            SELECT id, project_id FROM new_rows
+        ) changed;
+    END IF;
+
+    FOR change IN
+        SELECT project_id, array_agg(id ORDER BY id) AS ids
+        FROM (
+            SELECT c.id, c.project_id,
+                (row_number() OVER (PARTITION BY c.project_id ORDER BY c.id) - 1) / 500 AS batch
+            FROM unnest(changed_ids, changed_projects) AS c(id, project_id)
+        ) numbered
+        GROUP BY project_id, batch
+    LOOP
+        PERFORM pg_notify('goods_changed', json_build_object('project_id', change.project_id, 'ids', change.ids)::text);
+    END LOOP;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Построчный триггер прежних версий
+DROP TRIGGER IF EXISTS goods_changed ON goods;
+
+DROP TRIGGER IF EXISTS goods_inserted ON goods;
+CREATE TRIGGER goods_inserted
+AFTER INSERT ON goods
+REFERENCING NEW TABLE AS new_rows
+FOR EACH STATEMENT EXECUTE FUNCTION notify_goods_changed();
+
+DROP TRIGGER IF EXISTS goods_updated ON goods;
+CREATE TRIGGER goods_updated
+AFTER UPDATE ON goods
+REFERENCING OLD TABLE AS old_rows NEW TABLE AS new_rows
+FOR EACH STATEMENT EXECUTE FUNCTION notify_goods_changed();
+
+DROP TRIGGER IF EXISTS goods_deleted ON goods;
+CREATE TRIGGER goods_deleted
+AFTER DELETE ON goods
+REFERENCING OLD TABLE AS old_rows
+FOR EACH STATEMENT EXECUTE FUNCTION notify_goods_changed();
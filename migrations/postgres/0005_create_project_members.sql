@@ -0,0 +1,14 @@
+-- Роли субъектов в проектах: viewer читает товары проекта, editor
+-- ещё и изменяет их, admin ещё и управляет участниками проекта.
+-- subject — claim sub токена или apikey:<prefix> для ключей API.
+CREATE TABLE IF NOT EXISTS project_members (
+    project_id INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    subject TEXT NOT NULL,
+    role TEXT NOT NULL CHECK (role IN ('viewer', 'editor', 'admin')),
+    created_by TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (project_id, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_project_members_subject ON project_members(subject);
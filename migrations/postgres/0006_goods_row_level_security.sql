@@ -0,0 +1,41 @@
+-- Изоляция проектов на уровне строк. Репозиторий выполняет каждую транзакцию
+-- с goods под ролью goods_service и записывает в app.project_ids доступные
+-- субъекту проекты через запятую или * для всех. Политики скрывают от этой
+-- роли строки остальных проектов, даже если в запросе забыто условие на
+-- project_id. Без настройки не видна ни одна строка.
+--
+-- Суперпользователь и владелец таблиц политики не проверяют, поэтому
+-- переключение на goods_service обязательно. Миграции выполняет владелец,
+-- он же получает право переключаться на роль.
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT FROM pg_roles WHERE rolname = 'goods_service') THEN
+        CREATE ROLE goods_service NOLOGIN;
+    END IF;
+END
+$$;
+
+GRANT goods_service TO CURRENT_USER;
+GRANT SELECT, INSERT, UPDATE ON goods TO goods_service;
+GRANT USAGE ON SEQUENCE goods_id_seq TO goods_service;
+GRANT SELECT ON projects TO goods_service;
+
+-- CASE гарантирует, что * не будет приведена к INTEGER[]
+CREATE OR REPLACE FUNCTION app_project_visible(project_id INTEGER) RETURNS BOOLEAN AS $$
+    SELECT CASE COALESCE(current_setting('app.project_ids', true), '')
+        WHEN '*' THEN true
+        ELSE project_id = ANY (string_to_array(current_setting('app.project_ids', true), ',')::INTEGER[])
+    END
+$$ LANGUAGE sql STABLE;
+
+ALTER TABLE goods ENABLE ROW LEVEL SECURITY;
+ALTER TABLE projects ENABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS goods_project_isolation ON goods;
+CREATE POLICY goods_project_isolation ON goods TO goods_service
+    USING (app_project_visible(project_id))
+    WITH CHECK (app_project_visible(project_id));
+
+DROP POLICY IF EXISTS projects_isolation ON projects;
+CREATE POLICY projects_isolation ON projects TO goods_service
+    USING (app_project_visible(id));